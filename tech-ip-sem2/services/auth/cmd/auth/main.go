@@ -1,27 +1,104 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
 	grp "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/grpc"
+	handlers "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/http"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/store"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/token"
 	pb "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/proto/auth"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/logger"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
 )
 
 func main() {
 	// Инициализация структурированного логгера
 	logrusLogger := logger.Init("auth")
 
+	usersFile := os.Getenv("AUTH_USERS_FILE")
+	users, err := openUserStore(usersFile)
+	if err != nil {
+		logrusLogger.WithError(err).Fatal("failed to open user store")
+	}
+	// Подкоманда управления пользователями: auth users add|disable|enable|delete|list.
+	// Ей нужны только пользователи, поэтому ключи подписи и хранилища токенов не открываются.
+	if len(os.Args) > 1 && os.Args[1] == "users" {
+		if err := runUsersCommand(users, usersFile, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	tokens, err := newTokenManager(logrusLogger)
+	if err != nil {
+		logrusLogger.WithError(err).Fatal("failed to configure token signing")
+	}
+	refreshTTL, err := durationFromEnv("AUTH_REFRESH_TTL", 30*24*time.Hour)
+	if err != nil {
+		logrusLogger.WithError(err).Fatal("failed to configure refresh tokens")
+	}
+	revocations, err := openRevocationStore(os.Getenv("AUTH_REVOCATIONS_FILE"))
+	if err != nil {
+		logrusLogger.WithError(err).Fatal("failed to open revocation store")
+	}
+	refresh, err := openRefreshStore(os.Getenv("AUTH_REFRESH_FILE"))
+	if err != nil {
+		logrusLogger.WithError(err).Fatal("failed to open refresh token store")
+	}
+	authService := service.NewAuthService(users, refresh, revocations, tokens, refreshTTL)
+
+	if err := ensureDefaultUser(authService, logrusLogger); err != nil {
+		logrusLogger.WithError(err).Fatal("failed to create default user")
+	}
+
+	// Период ротации ключей подписи; 0 отключает ротацию
+	rotation, err := durationFromEnv("AUTH_KEY_ROTATION", 24*time.Hour)
+	if err != nil {
+		logrusLogger.WithError(err).Fatal("failed to configure key rotation")
+	}
+	// Ключ из AUTH_SIGNING_KEY_FILE постоянный: ротированный ключ не пережил бы перезапуск,
+	// и подписанные им токены перестали бы проверяться
+	if rotation > 0 && os.Getenv("AUTH_SIGNING_KEY_FILE") != "" {
+		logrusLogger.Warn("signing key rotation is disabled for AUTH_SIGNING_KEY_FILE, use AUTH_SIGNING_KEYS_DIR to rotate keys")
+		rotation = 0
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if rotation > 0 {
+		go tokens.Keys().Run(ctx, rotation, func(kid string, err error) {
+			if err != nil {
+				logrusLogger.WithError(err).Error("signing key rotation failed")
+				return
+			}
+			logrusLogger.WithField("kid", kid).Info("signing key rotated")
+		})
+	}
+	go pruneExpired(ctx, authService, logrusLogger)
+
 	grpcPort := os.Getenv("AUTH_GRPC_PORT")
 	if grpcPort == "" {
 		grpcPort = "50051"
 	}
+	httpPort := os.Getenv("AUTH_HTTP_PORT")
+	if httpPort == "" {
+		httpPort = "8081"
+	}
 
 	lis, err := net.Listen("tcp", ":"+grpcPort)
 	if err != nil {
@@ -29,7 +106,7 @@ func main() {
 	}
 
 	s := grpc.NewServer()
-	pb.RegisterAuthServiceServer(s, &grp.Server{Logger: logrusLogger})
+	pb.RegisterAuthServiceServer(s, &grp.Server{Service: authService, Logger: logrusLogger})
 	reflection.Register(s)
 
 	go func() {
@@ -39,9 +116,143 @@ func main() {
 		}
 	}()
 
+	authHandler := handlers.NewAuthHandler(authService)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/login", authHandler.LoginHandler)
+	mux.HandleFunc("GET /v1/auth/verify", authHandler.VerifyHandler)
+	mux.HandleFunc("POST /v1/auth/refresh", authHandler.RefreshHandler)
+	mux.HandleFunc("POST /v1/auth/logout", authHandler.LogoutHandler)
+	mux.HandleFunc("GET /.well-known/jwks.json", authHandler.JWKSHandler)
+
+	// RequestIDMiddleware должен идти первым
+	httpServer := &http.Server{
+		Addr:              ":" + httpPort,
+		Handler:           middleware.RequestIDMiddleware(middleware.LoggingMiddleware(mux)),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		logrusLogger.WithField("port", httpPort).Info("Auth HTTP server starting")
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrusLogger.WithError(err).Fatal("failed to serve http")
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	logrusLogger.Info("Shutting down Auth gRPC server...")
-	s.GracefulStop()
+	logrusLogger.Info("Shutting down Auth servers...")
+
+	// Останавливаем оба сервера параллельно, дожидаясь завершения текущих запросов
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logrusLogger.WithError(err).Error("http server shutdown failed")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		s.GracefulStop()
+	}()
+	wg.Wait()
+}
+
+// newTokenManager настраивает выпуск JWT по переменным окружения
+func newTokenManager(logger *logrus.Logger) (*token.Manager, error) {
+	issuer := os.Getenv("AUTH_ISSUER")
+	if issuer == "" {
+		issuer = "auth"
+	}
+	audience := os.Getenv("AUTH_AUDIENCE")
+	if audience == "" {
+		audience = "tasks"
+	}
+
+	ttl, err := durationFromEnv("AUTH_TOKEN_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	// По умолчанию старый ключ принимается, пока не истекут все подписанные им токены
+	grace, err := durationFromEnv("AUTH_KEY_GRACE", ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	keyFile, keyDir := os.Getenv("AUTH_SIGNING_KEY_FILE"), os.Getenv("AUTH_SIGNING_KEYS_DIR")
+	var keys *token.KeySet
+	switch {
+	case keyFile != "" && keyDir != "":
+		return nil, errors.New("AUTH_SIGNING_KEY_FILE and AUTH_SIGNING_KEYS_DIR are mutually exclusive")
+	case keyDir != "":
+		if keys, err = token.OpenKeyDir(keyDir, grace); err != nil {
+			return nil, err
+		}
+	default:
+		var key ed25519.PrivateKey
+		if keyFile != "" {
+			key, err = token.LoadKey(keyFile)
+		} else {
+			// Без ключа на диске токены перестанут проходить проверку после рестарта
+			logger.Warn("neither AUTH_SIGNING_KEY_FILE nor AUTH_SIGNING_KEYS_DIR is set, using ephemeral signing key")
+			key, err = token.GenerateKey()
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = token.NewKeySet(key, grace)
+	}
+
+	return token.NewManager(keys, issuer, audience, ttl), nil
+}
+
+// durationFromEnv читает длительность из переменной окружения
+func durationFromEnv(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// openRevocationStore выбирает хранилище списка отзыва: файл, если задан путь, иначе память
+func openRevocationStore(path string) (store.RevocationStore, error) {
+	if path == "" {
+		return store.NewMemoryRevocationStore(), nil
+	}
+	return store.NewFileRevocationStore(path)
+}
+
+// openRefreshStore выбирает хранилище refresh-токенов: файл, если задан путь, иначе память
+func openRefreshStore(path string) (store.RefreshStore, error) {
+	if path == "" {
+		return store.NewMemoryRefreshStore(), nil
+	}
+	return store.NewFileRefreshStore(path)
+}
+
+// pruneExpired периодически удаляет истёкшие refresh-токены и записи списка отзыва
+func pruneExpired(ctx context.Context, as *service.AuthService, logger *logrus.Logger) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := as.PruneExpired(now); n > 0 {
+				logger.WithField("count", n).Debug("expired tokens pruned")
+			}
+		}
+	}
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/store"
+)
+
+const usersUsage = "usage: auth users add <username> <password> [role,...] | roles <username> <role,...> | disable <username> | enable <username> | delete <username> | list"
+
+// openUserStore выбирает хранилище пользователей: файл, если задан путь, иначе память
+func openUserStore(path string) (store.UserStore, error) {
+	if path == "" {
+		return store.NewMemoryUserStore(), nil
+	}
+	return store.NewFileUserStore(path)
+}
+
+// ensureDefaultUser заводит пользователя по умолчанию, если хранилище пустое,
+// чтобы сервис можно было запустить "из коробки"
+func ensureDefaultUser(as *service.AuthService, logger *logrus.Logger) error {
+	users, err := as.ListUsers()
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return nil
+	}
+
+	username := os.Getenv("AUTH_DEFAULT_USER")
+	if username == "" {
+		username = "student"
+	}
+	password := os.Getenv("AUTH_DEFAULT_PASSWORD")
+	if password == "" {
+		password = "student"
+	}
+
+	if _, err := as.CreateUser(username, password, nil); err != nil {
+		return err
+	}
+	logger.WithField("username", username).Warn("user store is empty, default user created")
+	return nil
+}
+
+// runUsersCommand выполняет подкоманду auth users. Без AUTH_USERS_FILE изменения
+// попали бы в хранилище в памяти и пропали бы вместе с процессом, поэтому команда отказывается работать.
+// Запущенный сервер с тем же файлом видит изменения со следующего запроса.
+func runUsersCommand(users store.UserStore, usersFile string, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usersUsage)
+	}
+	if usersFile == "" {
+		return errors.New("AUTH_USERS_FILE is not set: users command requires a persistent user store")
+	}
+	// Управление пользователями не выпускает токены, поэтому остальные зависимости не нужны
+	as := service.NewAuthService(users, nil, nil, nil, 0)
+
+	switch {
+	case args[0] == "add" && (len(args) == 3 || len(args) == 4):
+		var roles []string
+		if len(args) == 4 {
+			roles = strings.Split(args[3], ",")
+		}
+		if _, err := as.CreateUser(args[1], args[2], roles); err != nil {
+			return err
+		}
+	case args[0] == "roles" && len(args) == 3:
+		if err := as.SetRoles(args[1], strings.Split(args[2], ",")); err != nil {
+			return err
+		}
+	case args[0] == "disable" && len(args) == 2:
+		if err := as.DisableUser(args[1]); err != nil {
+			return err
+		}
+	case args[0] == "enable" && len(args) == 2:
+		if err := as.EnableUser(args[1]); err != nil {
+			return err
+		}
+	case args[0] == "delete" && len(args) == 2:
+		if err := as.DeleteUser(args[1]); err != nil {
+			return err
+		}
+	case args[0] == "list" && len(args) == 1:
+		users, err := as.ListUsers()
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			state := "active"
+			if u.Disabled {
+				state = "disabled"
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", u.Username, state, strings.Join(u.Roles, ","), u.CreatedAt.Format(time.RFC3339))
+		}
+	default:
+		return errors.New(usersUsage)
+	}
+	return nil
+}
@@ -3,10 +3,14 @@ module github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth
 go 1.22
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/google/uuid v1.6.0
 	github.com/sirupsen/logrus v1.9.4
 	github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/proto v0.0.0
 	github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared v0.0.0-00010101000000-000000000000
+	golang.org/x/crypto v0.21.0
 	google.golang.org/grpc v1.64.0
+	google.golang.org/protobuf v1.33.0
 )
 
 require (
@@ -14,7 +18,6 @@ require (
 	golang.org/x/sys v0.18.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
-	google.golang.org/protobuf v1.33.0 // indirect
 )
 
 replace (
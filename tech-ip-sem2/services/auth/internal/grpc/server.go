@@ -2,45 +2,205 @@ package grpc
 
 import (
 	"context"
+	"errors"
 
 	"github.com/sirupsen/logrus"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/token"
 	pb "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/proto/auth"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Server struct {
 	pb.UnimplementedAuthServiceServer
-	Logger *logrus.Logger
+	Service *service.AuthService
+	Logger  *logrus.Logger
 }
 
-func (s *Server) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.VerifyResponse, error) {
-	// Извлекаем request-id из входящих метаданных
-	var requestID string
+// requestIDFromContext извлекает request-id из входящих метаданных
+func requestIDFromContext(ctx context.Context) string {
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		if values := md.Get("x-request-id"); len(values) > 0 {
-			requestID = values[0]
+			return values[0]
 		}
 	}
+	return ""
+}
+
+func (s *Server) Login(ctx context.Context, req *pb.LoginRequest) (*pb.TokenResponse, error) {
+	logEntry := s.Logger.WithFields(logrus.Fields{
+		"component":  "grpc_server",
+		"request_id": requestIDFromContext(ctx),
+		"username":   req.Username,
+	})
+
+	pair, err := s.Service.Login(req.Username, req.Password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		logEntry.Warn("invalid credentials")
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logEntry.WithError(err).Error("login failed")
+		return nil, status.Error(codes.Internal, "login failed")
+	}
+
+	logEntry.Info("user logged in")
+	return toTokenResponse(pair), nil
+}
 
+func (s *Server) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.VerifyResponse, error) {
 	logEntry := s.Logger.WithFields(logrus.Fields{
 		"component":     "grpc_server",
-		"request_id":    requestID,
+		"request_id":    requestIDFromContext(ctx),
 		"token_present": req.Token != "",
 	})
 
-	valid, subject := service.VerifyToken(req.Token)
-	if !valid {
-		logEntry.Warn("invalid token attempt")
-		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	claims, err := s.Service.VerifyToken(req.Token)
+	if err != nil {
+		logEntry.WithField("reason", err.Error()).Warn("invalid token attempt")
+		return nil, status.Error(codes.Unauthenticated, err.Error())
 	}
 
-	logEntry.WithField("subject", subject).Info("token verified successfully")
+	logEntry.WithField("subject", claims.Subject).Info("token verified successfully")
 
 	return &pb.VerifyResponse{
 		Valid:   true,
-		Subject: subject,
+		Subject: claims.Subject,
+		Roles:   claims.Roles,
+		Scopes:  claims.Scopes(),
 	}, nil
 }
+
+// GetKeys отдаёт открытые ключи подписи, чтобы клиенты могли проверять токены локально
+func (s *Server) GetKeys(ctx context.Context, req *pb.GetKeysRequest) (*pb.GetKeysResponse, error) {
+	keys := s.Service.PublicKeys()
+	resp := &pb.GetKeysResponse{Keys: make([]*pb.JWK, 0, len(keys))}
+	for _, k := range keys {
+		resp.Keys = append(resp.Keys, &pb.JWK{
+			Kid: k.Kid,
+			Kty: k.Kty,
+			Crv: k.Crv,
+			X:   k.X,
+			Alg: k.Alg,
+			Use: k.Use,
+		})
+	}
+
+	s.Logger.WithFields(logrus.Fields{
+		"component":  "grpc_server",
+		"request_id": requestIDFromContext(ctx),
+		"count":      len(keys),
+	}).Debug("signing keys listed")
+
+	return resp, nil
+}
+
+func (s *Server) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.TokenResponse, error) {
+	logEntry := s.Logger.WithFields(logrus.Fields{
+		"component":  "grpc_server",
+		"request_id": requestIDFromContext(ctx),
+	})
+
+	pair, err := s.Service.Refresh(req.RefreshToken)
+	if errors.Is(err, service.ErrRefreshReused) {
+		logEntry.Warn("refresh token reuse detected, session revoked")
+		return nil, status.Error(codes.Unauthenticated, service.ErrInvalidRefresh.Error())
+	}
+	if err != nil {
+		logEntry.WithField("reason", err.Error()).Warn("refresh failed")
+		return nil, status.Error(codes.Unauthenticated, service.ErrInvalidRefresh.Error())
+	}
+
+	logEntry.Info("tokens refreshed")
+	return toTokenResponse(pair), nil
+}
+
+// Revoke отзывает access-токен и закрывает его сессию
+func (s *Server) Revoke(ctx context.Context, req *pb.RevokeRequest) (*pb.RevokeResponse, error) {
+	logEntry := s.Logger.WithFields(logrus.Fields{
+		"component":  "grpc_server",
+		"request_id": requestIDFromContext(ctx),
+	})
+
+	if err := s.Service.Revoke(req.Token); err != nil {
+		if errors.Is(err, token.ErrInvalidToken) || errors.Is(err, token.ErrTokenExpired) {
+			logEntry.WithField("reason", err.Error()).Warn("revoke of invalid token")
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		logEntry.WithError(err).Error("revoke failed")
+		return nil, status.Error(codes.Internal, "revoke failed")
+	}
+
+	logEntry.Info("token revoked")
+	return &pb.RevokeResponse{}, nil
+}
+
+// WhoAmI возвращает сведения о владельце токена
+func (s *Server) WhoAmI(ctx context.Context, req *pb.WhoAmIRequest) (*pb.WhoAmIResponse, error) {
+	logEntry := s.Logger.WithFields(logrus.Fields{
+		"component":  "grpc_server",
+		"request_id": requestIDFromContext(ctx),
+	})
+
+	claims, err := s.Service.VerifyToken(req.Token)
+	if err != nil {
+		logEntry.WithField("reason", err.Error()).Warn("invalid token attempt")
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	logEntry.WithField("subject", claims.Subject).Debug("whoami")
+	return &pb.WhoAmIResponse{
+		Subject:   claims.Subject,
+		Roles:     claims.Roles,
+		ExpiresAt: timestamppb.New(claims.ExpiresAt.Time),
+		SessionId: claims.SessionID,
+		Scopes:    claims.Scopes(),
+	}, nil
+}
+
+// ListSessions возвращает активные сессии владельца токена
+func (s *Server) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	logEntry := s.Logger.WithFields(logrus.Fields{
+		"component":  "grpc_server",
+		"request_id": requestIDFromContext(ctx),
+	})
+
+	sessions, claims, err := s.Service.ListSessions(req.Token)
+	if errors.Is(err, token.ErrInvalidToken) || errors.Is(err, token.ErrTokenExpired) || errors.Is(err, token.ErrTokenRevoked) {
+		logEntry.WithField("reason", err.Error()).Warn("invalid token attempt")
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		logEntry.WithError(err).Error("list sessions failed")
+		return nil, status.Error(codes.Internal, "list sessions failed")
+	}
+
+	resp := &pb.ListSessionsResponse{Sessions: make([]*pb.Session, 0, len(sessions))}
+	for _, sess := range sessions {
+		resp.Sessions = append(resp.Sessions, &pb.Session{
+			Id:              sess.ID,
+			CreatedAt:       timestamppb.New(sess.CreatedAt),
+			LastRefreshedAt: timestamppb.New(sess.LastRefreshedAt),
+			ExpiresAt:       timestamppb.New(sess.ExpiresAt),
+			Current:         sess.ID == claims.SessionID,
+		})
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"subject": claims.Subject,
+		"count":   len(sessions),
+	}).Debug("sessions listed")
+	return resp, nil
+}
+
+func toTokenResponse(pair service.TokenPair) *pb.TokenResponse {
+	return &pb.TokenResponse{
+		AccessToken:  pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(pair.ExpiresIn.Seconds()),
+		RefreshToken: pair.RefreshToken,
+	}
+}
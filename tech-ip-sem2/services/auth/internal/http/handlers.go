@@ -2,50 +2,95 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/token"
 )
 
+// AuthHandler обслуживает HTTP API Auth service
+type AuthHandler struct {
+	authService *service.AuthService
+}
+
+// NewAuthHandler создаёт новый экземпляр обработчика
+func NewAuthHandler(as *service.AuthService) *AuthHandler {
+	return &AuthHandler{authService: as}
+}
+
 type loginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
 type loginResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func toLoginResponse(pair service.TokenPair) loginResponse {
+	return loginResponse{
+		AccessToken:  pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(pair.ExpiresIn.Seconds()),
+		RefreshToken: pair.RefreshToken,
+	}
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	var req loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	token, err := service.Login(req.Username, req.Password)
+	pair, err := h.authService.Login(req.Username, req.Password)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	resp := loginResponse{
-		AccessToken: token,
-		TokenType:   "Bearer",
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(toLoginResponse(pair))
+}
+
+// RefreshHandler обрабатывает POST /v1/auth/refresh
+func (h *AuthHandler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
+
+	pair, err := h.authService.Refresh(req.RefreshToken)
+	if err != nil {
+		http.Error(w, service.ErrInvalidRefresh.Error(), http.StatusUnauthorized)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(toLoginResponse(pair))
 }
 
 type verifyResponse struct {
-	Valid   bool   `json:"valid"`
-	Subject string `json:"subject,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Valid   bool     `json:"valid"`
+	Subject string   `json:"subject,omitempty"`
+	Roles   []string `json:"roles,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+	Error   string   `json:"error,omitempty"`
 }
 
-func VerifyHandler(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) VerifyHandler(w http.ResponseWriter, r *http.Request) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -61,13 +106,50 @@ func VerifyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	valid, subject := service.VerifyToken(token)
-	if !valid {
+	claims, err := h.authService.VerifyToken(token)
+	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(verifyResponse{Valid: false, Error: "invalid token"})
+		json.NewEncoder(w).Encode(verifyResponse{Valid: false, Error: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verifyResponse{
+		Valid:   true,
+		Subject: claims.Subject,
+		Roles:   claims.Roles,
+		Scopes:  claims.Scopes(),
+	})
+}
+
+// LogoutHandler обрабатывает POST /v1/auth/logout: отзывает предъявленный
+// access-токен и закрывает сессию, к которой он относится
+func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var raw string
+	if _, err := fmt.Sscanf(r.Header.Get("Authorization"), "Bearer %s", &raw); err != nil {
+		http.Error(w, "missing or invalid authorization header", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authService.Revoke(raw); err != nil {
+		if errors.Is(err, token.ErrInvalidToken) || errors.Is(err, token.ErrTokenExpired) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "logout failed", http.StatusInternalServerError)
 		return
 	}
 
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type jwksResponse struct {
+	Keys []token.JWK `json:"keys"`
+}
+
+// JWKSHandler отдаёт открытые ключи подписи в виде JWK Set (RFC 7517)
+func (h *AuthHandler) JWKSHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(verifyResponse{Valid: true, Subject: subject})
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(jwksResponse{Keys: h.authService.PublicKeys()})
 }
@@ -1,24 +1,287 @@
 package service
 
-import "errors"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
 
-const (
-	validUsername = "student"
-	validPassword = "student"
-	validToken    = "demo-token"
-	subject       = "student"
+	"github.com/google/uuid"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/store"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/token"
+	"golang.org/x/crypto/bcrypt"
 )
 
-func Login(username, password string) (string, error) {
-	if username == validUsername && password == validPassword {
-		return validToken, nil
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidUser        = errors.New("username and password are required")
+	ErrInvalidRefresh     = errors.New("invalid refresh token")
+	ErrRefreshReused      = errors.New("refresh token reuse detected")
+)
+
+// DefaultRole назначается пользователю, если роли не указаны при создании
+const DefaultRole = "user"
+
+// dummyHash используется, когда пользователь не найден, чтобы время ответа
+// Login не выдавало существование логина
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// AuthService выполняет вход пользователей и проверку выданных токенов
+type AuthService struct {
+	users       store.UserStore
+	refresh     store.RefreshStore
+	revocations store.RevocationStore
+	tokens      *token.Manager
+	refreshTTL  time.Duration
+}
+
+func NewAuthService(users store.UserStore, refresh store.RefreshStore, revocations store.RevocationStore, tokens *token.Manager, refreshTTL time.Duration) *AuthService {
+	return &AuthService{
+		users:       users,
+		refresh:     refresh,
+		revocations: revocations,
+		tokens:      tokens,
+		refreshTTL:  refreshTTL,
+	}
+}
+
+// TokenPair — результат входа или обновления токенов
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// CreateUser заводит нового пользователя с bcrypt-хэшем пароля
+func (s *AuthService) CreateUser(username, password string, roles []string) (store.User, error) {
+	if username == "" || password == "" {
+		return store.User{}, ErrInvalidUser
+	}
+	if len(roles) == 0 {
+		roles = []string{DefaultRole}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return store.User{}, fmt.Errorf("hash password: %w", err)
+	}
+	now := time.Now()
+	user := store.User{
+		Username:     username,
+		PasswordHash: string(hash),
+		Roles:        roles,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.users.Create(user); err != nil {
+		return store.User{}, err
+	}
+	return user, nil
+}
+
+// SetRoles заменяет роли пользователя; новые роли попадут в токены при следующем входе или обновлении
+func (s *AuthService) SetRoles(username string, roles []string) error {
+	user, err := s.users.Get(username)
+	if err != nil {
+		return err
+	}
+	user.Roles = roles
+	user.UpdatedAt = time.Now()
+	return s.users.Update(user)
+}
+
+// DisableUser запрещает вход пользователю и делает недействительными его токены
+func (s *AuthService) DisableUser(username string) error {
+	return s.setDisabled(username, true)
+}
+
+// EnableUser снимает блокировку с пользователя
+func (s *AuthService) EnableUser(username string) error {
+	return s.setDisabled(username, false)
+}
+
+func (s *AuthService) setDisabled(username string, disabled bool) error {
+	user, err := s.users.Get(username)
+	if err != nil {
+		return err
+	}
+	user.Disabled = disabled
+	user.UpdatedAt = time.Now()
+	return s.users.Update(user)
+}
+
+// DeleteUser удаляет пользователя; выданные ему токены перестают проходить проверку
+func (s *AuthService) DeleteUser(username string) error {
+	return s.users.Delete(username)
+}
+
+func (s *AuthService) ListUsers() ([]store.User, error) {
+	return s.users.List()
+}
+
+func (s *AuthService) Login(username, password string) (TokenPair, error) {
+	user, err := s.users.Get(username)
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return TokenPair{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return TokenPair{}, ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return TokenPair{}, ErrInvalidCredentials
+	}
+
+	return s.issuePair(user, uuid.NewString())
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов. Каждый refresh-токен
+// одноразовый: повторное предъявление уже использованного токена означает,
+// что он утёк, поэтому вся сессия отзывается вместе с выданными в ней access-токенами.
+func (s *AuthService) Refresh(refreshToken string) (TokenPair, error) {
+	rt, err := s.refresh.Use(hashToken(refreshToken), time.Now())
+	if errors.Is(err, store.ErrRefreshTokenUsed) {
+		if err := s.revokeSession(rt.SessionID); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrRefreshReused
+	}
+	if err != nil {
+		return TokenPair{}, ErrInvalidRefresh
 	}
-	return "", errors.New("invalid credentials")
+
+	user, err := s.users.Get(rt.Username)
+	if err != nil || user.Disabled {
+		s.refresh.RevokeSession(rt.SessionID)
+		return TokenPair{}, ErrInvalidRefresh
+	}
+
+	return s.issuePair(user, rt.SessionID)
+}
+
+// Revoke отзывает access-токен: его jti попадает в список отзыва до истечения срока
+// действия, а сессия, к которой он относится, закрывается вместе с refresh-токенами
+// и остальными access-токенами
+func (s *AuthService) Revoke(raw string) error {
+	claims, err := s.tokens.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if err := s.revocations.Revoke(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	if claims.SessionID != "" {
+		return s.revokeSession(claims.SessionID)
+	}
+	return nil
+}
+
+// revokeSession удаляет refresh-токены сессии и вносит её в список отзыва, чтобы
+// VerifyToken отклонял все выданные в ней access-токены. Запись нужна, пока не истечёт
+// самый поздний из них, то есть на время жизни access-токена.
+func (s *AuthService) revokeSession(sessionID string) error {
+	if err := s.revocations.Revoke(sessionRevocationKey(sessionID), time.Now().Add(s.tokens.TTL())); err != nil {
+		return fmt.Errorf("revoke session tokens: %w", err)
+	}
+	if err := s.refresh.RevokeSession(sessionID); err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	return nil
+}
+
+// sessionRevocationKey — ключ сессии в списке отзыва; префикс не даёт ему совпасть с jti
+func sessionRevocationKey(sessionID string) string {
+	return "sid:" + sessionID
+}
+
+// ListSessions возвращает активные сессии владельца токена
+func (s *AuthService) ListSessions(raw string) ([]store.Session, *token.Claims, error) {
+	claims, err := s.VerifyToken(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	sessions, err := s.refresh.ListSessions(claims.Subject, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("list sessions: %w", err)
+	}
+	return sessions, claims, nil
+}
+
+// PruneExpired удаляет истёкшие refresh-токены и записи списка отзыва
+func (s *AuthService) PruneExpired(now time.Time) int {
+	return s.refresh.PruneExpired(now) + s.revocations.PruneExpired(now)
 }
 
-func VerifyToken(token string) (bool, string) {
-	if token == validToken {
-		return true, subject
+func (s *AuthService) issuePair(user store.User, sessionID string) (TokenPair, error) {
+	roles := user.Roles
+	if len(roles) == 0 {
+		roles = []string{DefaultRole}
 	}
-	return false, ""
+	access, _, err := s.tokens.Issue(user.Username, sessionID, roles, ScopesForRoles(roles))
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	raw, err := newRefreshToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	now := time.Now()
+	err = s.refresh.Save(store.RefreshToken{
+		Hash:      hashToken(raw),
+		SessionID: sessionID,
+		Username:  user.Username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.refreshTTL),
+	})
+	if err != nil {
+		return TokenPair{}, fmt.Errorf("save refresh token: %w", err)
+	}
+
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: raw,
+		ExpiresIn:    s.tokens.TTL(),
+	}, nil
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate refresh token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyToken проверяет подпись и срок действия токена, что он не отозван,
+// а также то, что его владелец всё ещё существует и не заблокирован
+func (s *AuthService) VerifyToken(raw string) (*token.Claims, error) {
+	claims, err := s.tokens.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if s.revocations.IsRevoked(claims.ID) {
+		return nil, token.ErrTokenRevoked
+	}
+	if claims.SessionID != "" && s.revocations.IsRevoked(sessionRevocationKey(claims.SessionID)) {
+		return nil, token.ErrTokenRevoked
+	}
+
+	user, err := s.users.Get(claims.Subject)
+	if err != nil || user.Disabled {
+		return nil, token.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// PublicKeys возвращает открытые ключи, которыми подписываются и проверяются токены
+func (s *AuthService) PublicKeys() []token.JWK {
+	return s.tokens.Keys().PublicKeys()
 }
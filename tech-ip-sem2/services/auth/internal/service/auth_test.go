@@ -0,0 +1,217 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/store"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/auth/internal/token"
+)
+
+func newTestService(t *testing.T) *AuthService {
+	t.Helper()
+	key, err := token.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := token.NewManager(token.NewKeySet(key, time.Minute), "auth", "tasks", time.Minute)
+	s := NewAuthService(store.NewMemoryUserStore(), store.NewMemoryRefreshStore(), store.NewMemoryRevocationStore(), tokens, time.Hour)
+	if _, err := s.CreateUser("alice", "secret", nil); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	s := newTestService(t)
+	first, err := s.Login("alice", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	second, err := s.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	third, err := s.Refresh(second.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh with rotated token: %v", err)
+	}
+
+	for name, pair := range map[string]TokenPair{"login": first, "first refresh": second, "second refresh": third} {
+		claims, err := s.VerifyToken(pair.AccessToken)
+		if err != nil {
+			t.Errorf("access token from %s: %v", name, err)
+			continue
+		}
+		if claims.SessionID == "" {
+			t.Errorf("access token from %s has no session", name)
+		}
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	s := newTestService(t)
+	first, err := s.Login("alice", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	other, err := s.Login("alice", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	second, err := s.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	if _, err := s.Refresh(first.RefreshToken); !errors.Is(err, ErrRefreshReused) {
+		t.Fatalf("Refresh with used token: err = %v, want %v", err, ErrRefreshReused)
+	}
+
+	// Отзываются и старый, и уже выданный после ротации access-токен
+	for name, raw := range map[string]string{"original": first.AccessToken, "rotated": second.AccessToken} {
+		if _, err := s.VerifyToken(raw); !errors.Is(err, token.ErrTokenRevoked) {
+			t.Errorf("%s access token: err = %v, want %v", name, err, token.ErrTokenRevoked)
+		}
+	}
+	if _, err := s.Refresh(second.RefreshToken); !errors.Is(err, ErrInvalidRefresh) {
+		t.Errorf("Refresh in revoked session: err = %v, want %v", err, ErrInvalidRefresh)
+	}
+
+	// Другая сессия того же пользователя не затронута
+	if _, err := s.VerifyToken(other.AccessToken); err != nil {
+		t.Errorf("access token of another session: %v", err)
+	}
+	if _, err := s.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("Refresh in another session: %v", err)
+	}
+}
+
+func TestRevokeClosesSession(t *testing.T) {
+	s := newTestService(t)
+	first, err := s.Login("alice", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	second, err := s.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	if err := s.Revoke(second.AccessToken); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := s.VerifyToken(first.AccessToken); !errors.Is(err, token.ErrTokenRevoked) {
+		t.Errorf("earlier access token of revoked session: err = %v, want %v", err, token.ErrTokenRevoked)
+	}
+	if _, err := s.Refresh(second.RefreshToken); !errors.Is(err, ErrInvalidRefresh) {
+		t.Errorf("Refresh after revoke: err = %v, want %v", err, ErrInvalidRefresh)
+	}
+}
+
+func TestRefreshRejectsUnknownToken(t *testing.T) {
+	s := newTestService(t)
+	if _, err := s.Refresh("not-a-token"); !errors.Is(err, ErrInvalidRefresh) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidRefresh)
+	}
+}
+
+func TestLoginChecksPassword(t *testing.T) {
+	s := newTestService(t)
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  error
+	}{
+		{"correct password", "alice", "secret", nil},
+		{"wrong password", "alice", "Secret", ErrInvalidCredentials},
+		{"empty password", "alice", "", ErrInvalidCredentials},
+		// Неизвестный логин неотличим от неверного пароля
+		{"unknown user", "mallory", "secret", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := s.Login(tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			claims, err := s.VerifyToken(pair.AccessToken)
+			if err != nil || claims.Subject != tt.username {
+				t.Fatalf("VerifyToken: claims %+v, err = %v", claims, err)
+			}
+		})
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	s := newTestService(t)
+	if _, err := s.CreateUser("alice", "other", nil); !errors.Is(err, store.ErrUserExists) {
+		t.Fatalf("CreateUser duplicate: err = %v, want %v", err, store.ErrUserExists)
+	}
+	for _, tt := range []struct{ username, password string }{{"", "secret"}, {"bob", ""}} {
+		if _, err := s.CreateUser(tt.username, tt.password, nil); !errors.Is(err, ErrInvalidUser) {
+			t.Errorf("CreateUser(%q, %q): err = %v, want %v", tt.username, tt.password, err, ErrInvalidUser)
+		}
+	}
+
+	user, err := s.CreateUser("bob", "pa55", nil)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if user.PasswordHash == "pa55" || len(user.Roles) != 1 || user.Roles[0] != DefaultRole {
+		t.Fatalf("created user = %+v, want bcrypt hash and role %q", user, DefaultRole)
+	}
+}
+
+// TestBlockedUserLosesAccess проверяет, что блокировка и удаление действуют
+// и на вход, и на уже выданные токены
+func TestBlockedUserLosesAccess(t *testing.T) {
+	tests := []struct {
+		name  string
+		block func(s *AuthService) error
+		// wantRestore — вернётся ли доступ после EnableUser
+		wantRestore bool
+	}{
+		{"disabled", func(s *AuthService) error { return s.DisableUser("alice") }, true},
+		{"deleted", func(s *AuthService) error { return s.DeleteUser("alice") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			pair, err := s.Login("alice", "secret")
+			if err != nil {
+				t.Fatalf("Login: %v", err)
+			}
+			if err := tt.block(s); err != nil {
+				t.Fatalf("block: %v", err)
+			}
+
+			if _, err := s.VerifyToken(pair.AccessToken); !errors.Is(err, token.ErrInvalidToken) {
+				t.Errorf("VerifyToken of issued token: err = %v, want %v", err, token.ErrInvalidToken)
+			}
+			if _, err := s.Login("alice", "secret"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Login: err = %v, want %v", err, ErrInvalidCredentials)
+			}
+
+			err = s.EnableUser("alice")
+			if tt.wantRestore != (err == nil) {
+				t.Fatalf("EnableUser: err = %v", err)
+			}
+			if !tt.wantRestore {
+				return
+			}
+			if _, err := s.VerifyToken(pair.AccessToken); err != nil {
+				t.Errorf("VerifyToken after enable: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import "sort"
+
+// Scopes, которые проверяет Tasks service
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeTasksAdmin = "tasks:admin"
+)
+
+// roleScopes задаёт, какие scopes получает владелец роли
+var roleScopes = map[string][]string{
+	"reader": {ScopeTasksRead},
+	"user":   {ScopeTasksRead, ScopeTasksWrite},
+	"admin":  {ScopeTasksRead, ScopeTasksWrite, ScopeTasksAdmin},
+}
+
+// ScopesForRoles объединяет scopes всех ролей; неизвестные роли scopes не дают
+func ScopesForRoles(roles []string) []string {
+	set := make(map[string]struct{})
+	for _, role := range roles {
+		for _, scope := range roleScopes[role] {
+			set[scope] = struct{}{}
+		}
+	}
+	scopes := make([]string, 0, len(set))
+	for scope := range set {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
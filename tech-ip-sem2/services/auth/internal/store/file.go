@@ -0,0 +1,156 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileUserStore хранит пользователей в JSON-файле.
+// Каждое изменение целиком перезаписывает файл через временный файл и rename,
+// чтобы при падении процесса не остаться с наполовину записанными данными.
+// Перед каждой операцией файл перечитывается, если его изменил другой процесс
+// (например, подкоманда auth users), поэтому блокировка пользователя действует
+// на запущенный сервер без перезапуска.
+type FileUserStore struct {
+	mu   sync.Mutex
+	path string
+	mem  *MemoryUserStore
+	// loaded — файл, прочитанный или записанный последним; nil, если файла ещё нет
+	loaded os.FileInfo
+}
+
+// NewFileUserStore открывает файл с пользователями, создавая его при отсутствии
+func NewFileUserStore(path string) (*FileUserStore, error) {
+	s := &FileUserStore{
+		path: path,
+		mem:  NewMemoryUserStore(),
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload перечитывает файл, если он изменился с последнего чтения или записи;
+// вызывается под s.mu. Файл заменяется через rename, поэтому изменение видно
+// и по новому inode, даже если время модификации совпало.
+func (s *FileUserStore) reload() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat users file: %w", err)
+	}
+	if s.loaded != nil && os.SameFile(s.loaded, info) &&
+		s.loaded.ModTime().Equal(info.ModTime()) && s.loaded.Size() == info.Size() {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("read users file: %w", err)
+	}
+	var users []User
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &users); err != nil {
+			return fmt.Errorf("parse users file: %w", err)
+		}
+	}
+	mem := NewMemoryUserStore()
+	for _, u := range users {
+		mem.users[u.Username] = u
+	}
+	s.mem = mem
+	s.loaded = info
+	return nil
+}
+
+func (s *FileUserStore) Create(user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if err := s.mem.Create(user); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *FileUserStore) Get(username string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return User{}, err
+	}
+	return s.mem.Get(username)
+}
+
+func (s *FileUserStore) Update(user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if err := s.mem.Update(user); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *FileUserStore) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if err := s.mem.Delete(username); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *FileUserStore) List() ([]User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s.mem.List()
+}
+
+// flush сохраняет текущее состояние на диск; вызывается под s.mu
+func (s *FileUserStore) flush() error {
+	users, _ := s.mem.List()
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode users: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("create users dir: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write users file: %w", err)
+	}
+	// Свою запись перечитывать не нужно. Stat до rename: после него файл мог
+	// успеть заменить другой процесс, и его изменение было бы пропущено.
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return fmt.Errorf("stat users file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replace users file: %w", err)
+	}
+	s.loaded = info
+	return nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileUserStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	s, err := NewFileUserStore(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	alice := User{Username: "alice", PasswordHash: "hash-a", Roles: []string{"user"}, CreatedAt: now, UpdatedAt: now}
+	bob := User{Username: "bob", PasswordHash: "hash-b", Roles: []string{"admin", "user"}, CreatedAt: now, UpdatedAt: now}
+	for _, u := range []User{alice, bob} {
+		if err := s.Create(u); err != nil {
+			t.Fatalf("Create %s: %v", u.Username, err)
+		}
+	}
+	if err := s.Create(User{Username: "alice", PasswordHash: "other"}); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("Create duplicate: err = %v, want %v", err, ErrUserExists)
+	}
+	bob.Disabled = true
+	if err := s.Update(bob); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	reopened, err := NewFileUserStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if want := []User{alice, bob}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("List after restart = %+v, want %+v", got, want)
+	}
+	if err := reopened.Create(User{Username: "bob"}); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("Create duplicate after restart: err = %v, want %v", err, ErrUserExists)
+	}
+}
+
+// TestFileUserStoreSeesExternalChanges — так работающий сервер видит изменения,
+// сделанные подкомандой auth users
+func TestFileUserStoreSeesExternalChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	server, err := NewFileUserStore(path)
+	if err != nil {
+		t.Fatalf("open server store: %v", err)
+	}
+	if err := server.Create(User{Username: "alice", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	cli, err := NewFileUserStore(path)
+	if err != nil {
+		t.Fatalf("open cli store: %v", err)
+	}
+	alice, err := cli.Get("alice")
+	if err != nil {
+		t.Fatalf("cli Get: %v", err)
+	}
+	alice.Disabled = true
+	if err := cli.Update(alice); err != nil {
+		t.Fatalf("cli Update: %v", err)
+	}
+	if err := cli.Create(User{Username: "bob", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("cli Create: %v", err)
+	}
+
+	if got, err := server.Get("alice"); err != nil || !got.Disabled {
+		t.Fatalf("server Get alice = %+v, err = %v; want disabled", got, err)
+	}
+	// Своё изменение сервера не затирает сделанное другим процессом
+	if err := server.Create(User{Username: "carol", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("server Create: %v", err)
+	}
+	if err := cli.Delete("bob"); err != nil {
+		t.Fatalf("cli Delete: %v", err)
+	}
+	if _, err := server.Get("bob"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("server Get deleted user: err = %v, want %v", err, ErrUserNotFound)
+	}
+	users, _ := server.List()
+	if len(users) != 2 || users[0].Username != "alice" || users[1].Username != "carol" {
+		t.Fatalf("server List = %+v, want alice and carol", users)
+	}
+
+	// Испорченный файл — ошибка, а не пустой список пользователей
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Get("alice"); err == nil {
+		t.Fatal("Get from corrupted file succeeded")
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryUserStore хранит пользователей в памяти процесса
+type MemoryUserStore struct {
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{
+		users: make(map[string]User),
+	}
+}
+
+func (s *MemoryUserStore) Create(user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[user.Username]; ok {
+		return ErrUserExists
+	}
+	s.users[user.Username] = user
+	return nil
+}
+
+func (s *MemoryUserStore) Get(username string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[username]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *MemoryUserStore) Update(user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[user.Username]; !ok {
+		return ErrUserNotFound
+	}
+	s.users[user.Username] = user
+	return nil
+}
+
+func (s *MemoryUserStore) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[username]; !ok {
+		return ErrUserNotFound
+	}
+	delete(s.users, username)
+	return nil
+}
+
+func (s *MemoryUserStore) List() ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
@@ -0,0 +1,254 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenUsed     = errors.New("refresh token already used")
+)
+
+// RefreshToken — запись о выданном refresh-токене. Сам токен не хранится, только его хэш.
+// Все токены, полученные цепочкой обновлений от одного входа, принадлежат одной сессии.
+type RefreshToken struct {
+	Hash      string    `json:"hash"`
+	SessionID string    `json:"session_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    time.Time `json:"used_at"`
+}
+
+// Session — вход пользователя и все последующие обновления токенов в его рамках
+type Session struct {
+	ID              string
+	Username        string
+	CreatedAt       time.Time
+	LastRefreshedAt time.Time
+	ExpiresAt       time.Time
+}
+
+// RefreshStore хранит refresh-токены и сессии, к которым они относятся
+type RefreshStore interface {
+	Save(t RefreshToken) error
+	// Use атомарно помечает токен использованным. Если токен уже был использован,
+	// возвращает его вместе с ErrRefreshTokenUsed — это признак кражи токена.
+	Use(hash string, at time.Time) (RefreshToken, error)
+	RevokeSession(sessionID string) error
+	// ListSessions возвращает активные сессии пользователя, то есть те,
+	// у которых остался неиспользованный и неистёкший refresh-токен
+	ListSessions(username string, now time.Time) ([]Session, error)
+	PruneExpired(now time.Time) int
+}
+
+// MemoryRefreshStore хранит refresh-токены в памяти процесса
+type MemoryRefreshStore struct {
+	mu     sync.Mutex
+	tokens map[string]RefreshToken
+}
+
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{
+		tokens: make(map[string]RefreshToken),
+	}
+}
+
+func (s *MemoryRefreshStore) Save(t RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[t.Hash] = t
+	return nil
+}
+
+func (s *MemoryRefreshStore) Use(hash string, at time.Time) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[hash]
+	if !ok || !at.Before(t.ExpiresAt) {
+		return RefreshToken{}, ErrRefreshTokenNotFound
+	}
+	if !t.UsedAt.IsZero() {
+		return t, ErrRefreshTokenUsed
+	}
+	t.UsedAt = at
+	s.tokens[hash] = t
+	return t, nil
+}
+
+func (s *MemoryRefreshStore) RevokeSession(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, t := range s.tokens {
+		if t.SessionID == sessionID {
+			delete(s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryRefreshStore) ListSessions(username string, now time.Time) ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := make(map[string]*Session)
+	active := make(map[string]bool)
+	for _, t := range s.tokens {
+		if t.Username != username {
+			continue
+		}
+		sess, ok := sessions[t.SessionID]
+		if !ok {
+			sess = &Session{ID: t.SessionID, Username: t.Username, CreatedAt: t.CreatedAt}
+			sessions[t.SessionID] = sess
+		}
+		if t.CreatedAt.Before(sess.CreatedAt) {
+			sess.CreatedAt = t.CreatedAt
+		}
+		if t.CreatedAt.After(sess.LastRefreshedAt) {
+			sess.LastRefreshedAt = t.CreatedAt
+		}
+		if t.ExpiresAt.After(sess.ExpiresAt) {
+			sess.ExpiresAt = t.ExpiresAt
+		}
+		if t.UsedAt.IsZero() && now.Before(t.ExpiresAt) {
+			active[t.SessionID] = true
+		}
+	}
+
+	result := make([]Session, 0, len(active))
+	for id := range active {
+		result = append(result, *sessions[id])
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
+func (s *MemoryRefreshStore) PruneExpired(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pruned := 0
+	for hash, t := range s.tokens {
+		if !now.Before(t.ExpiresAt) {
+			delete(s.tokens, hash)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// FileRefreshStore дублирует refresh-токены в JSON-файл, чтобы сессии переживали рестарт.
+// В файле, как и в памяти, хранятся только хэши токенов.
+type FileRefreshStore struct {
+	mu   sync.Mutex
+	path string
+	mem  *MemoryRefreshStore
+}
+
+// NewFileRefreshStore загружает refresh-токены из файла, создавая его при отсутствии
+func NewFileRefreshStore(path string) (*FileRefreshStore, error) {
+	s := &FileRefreshStore{
+		path: path,
+		mem:  NewMemoryRefreshStore(),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read refresh tokens file: %w", err)
+	}
+	var tokens []RefreshToken
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &tokens); err != nil {
+			return nil, fmt.Errorf("parse refresh tokens file: %w", err)
+		}
+	}
+	for _, t := range tokens {
+		s.mem.tokens[t.Hash] = t
+	}
+	s.mem.PruneExpired(time.Now())
+	return s, nil
+}
+
+func (s *FileRefreshStore) Save(t RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mem.Save(t)
+	return s.flush()
+}
+
+// Use сохраняет отметку об использовании до возврата токена: иначе после рестарта
+// тот же токен можно было бы предъявить ещё раз, не попав под обнаружение повтора
+func (s *FileRefreshStore) Use(hash string, at time.Time) (RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, err := s.mem.Use(hash, at)
+	if err != nil {
+		return t, err
+	}
+	if err := s.flush(); err != nil {
+		return RefreshToken{}, err
+	}
+	return t, nil
+}
+
+func (s *FileRefreshStore) RevokeSession(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mem.RevokeSession(sessionID)
+	return s.flush()
+}
+
+func (s *FileRefreshStore) ListSessions(username string, now time.Time) ([]Session, error) {
+	return s.mem.ListSessions(username, now)
+}
+
+func (s *FileRefreshStore) PruneExpired(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.mem.PruneExpired(now)
+	if n > 0 {
+		// Ошибка записи не критична: истёкшие токены не принимаются и будут удалены при следующей очистке
+		s.flush()
+	}
+	return n
+}
+
+// flush сохраняет текущее состояние на диск; вызывается под s.mu
+func (s *FileRefreshStore) flush() error {
+	s.mem.mu.Lock()
+	tokens := make([]RefreshToken, 0, len(s.mem.tokens))
+	for _, t := range s.mem.tokens {
+		tokens = append(tokens, t)
+	}
+	s.mem.mu.Unlock()
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode refresh tokens: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("create refresh tokens dir: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write refresh tokens file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replace refresh tokens file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRefreshStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "refresh.json")
+	now := time.Now()
+
+	s, err := NewFileRefreshStore(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, tok := range []RefreshToken{
+		{Hash: "used", SessionID: "s1", Username: "alice", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{Hash: "fresh", SessionID: "s1", Username: "alice", CreatedAt: now.Add(time.Second), ExpiresAt: now.Add(time.Hour)},
+		{Hash: "expired", SessionID: "s2", Username: "alice", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+	} {
+		if err := s.Save(tok); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	if _, err := s.Use("used", now); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+
+	reopened, err := NewFileRefreshStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	// Отметка об использовании сохранилась: повтор после рестарта распознаётся
+	if rt, err := reopened.Use("used", now); !errors.Is(err, ErrRefreshTokenUsed) || rt.SessionID != "s1" {
+		t.Errorf("Use of used token after restart: session %q, err = %v", rt.SessionID, err)
+	}
+	if _, err := reopened.Use("expired", now); !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Errorf("Use of expired token: err = %v, want %v", err, ErrRefreshTokenNotFound)
+	}
+
+	sessions, err := reopened.ListSessions("alice", now)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "s1" {
+		t.Fatalf("sessions = %+v, want only s1", sessions)
+	}
+
+	if err := reopened.RevokeSession("s1"); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	again, err := NewFileRefreshStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if _, err := again.Use("fresh", now); !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Errorf("Use in revoked session after restart: err = %v, want %v", err, ErrRefreshTokenNotFound)
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RevocationStore — список отозванных access-токенов (по jti).
+// Запись нужна только до истечения срока действия токена, после чего её можно удалить.
+type RevocationStore interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) bool
+	PruneExpired(now time.Time) int
+}
+
+// MemoryRevocationStore хранит отозванные токены в памяти процесса
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time // jti -> exp
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(jti string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.revoked[jti]
+	return ok
+}
+
+func (s *MemoryRevocationStore) PruneExpired(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pruned := 0
+	for jti, exp := range s.revoked {
+		if !now.Before(exp) {
+			delete(s.revoked, jti)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// FileRevocationStore дублирует список отзыва в JSON-файл, чтобы он переживал рестарт
+type FileRevocationStore struct {
+	mu   sync.Mutex
+	path string
+	mem  *MemoryRevocationStore
+}
+
+// NewFileRevocationStore загружает список отзыва из файла, создавая его при отсутствии
+func NewFileRevocationStore(path string) (*FileRevocationStore, error) {
+	s := &FileRevocationStore{
+		path: path,
+		mem:  NewMemoryRevocationStore(),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read revocations file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.mem.revoked); err != nil {
+			return nil, fmt.Errorf("parse revocations file: %w", err)
+		}
+	}
+	s.mem.PruneExpired(time.Now())
+	return s, nil
+}
+
+func (s *FileRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mem.Revoke(jti, expiresAt)
+	return s.flush()
+}
+
+func (s *FileRevocationStore) IsRevoked(jti string) bool {
+	return s.mem.IsRevoked(jti)
+}
+
+func (s *FileRevocationStore) PruneExpired(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.mem.PruneExpired(now)
+	if n > 0 {
+		// Ошибка записи не критична: устаревшие записи будут удалены при следующей очистке
+		s.flush()
+	}
+	return n
+}
+
+// flush сохраняет текущее состояние на диск; вызывается под s.mu
+func (s *FileRevocationStore) flush() error {
+	s.mem.mu.RLock()
+	data, err := json.Marshal(s.mem.revoked)
+	s.mem.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encode revocations: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("create revocations dir: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write revocations file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replace revocations file: %w", err)
+	}
+	return nil
+}
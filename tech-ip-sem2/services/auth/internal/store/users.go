@@ -0,0 +1,30 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user already exists")
+)
+
+// User описывает учётную запись пользователя Auth service
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	Roles        []string  `json:"roles"`
+	Disabled     bool      `json:"disabled"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UserStore — хранилище пользователей, за которым может стоять память, файл или БД
+type UserStore interface {
+	Create(user User) error
+	Get(username string) (User, error)
+	Update(user User) error
+	Delete(username string) error
+	List() ([]User, error)
+}
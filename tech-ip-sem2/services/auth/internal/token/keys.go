@@ -0,0 +1,292 @@
+package token
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWK — открытый ключ в формате JSON Web Key (RFC 8037 для Ed25519)
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+type signingKey struct {
+	id        string
+	private   ed25519.PrivateKey
+	createdAt time.Time
+	// retireAt — момент, после которого ключ больше не принимается при проверке.
+	// Нулевое значение у активного ключа.
+	retireAt time.Time
+}
+
+// KeySet хранит активный ключ подписи и предыдущие ключи, которые ещё
+// принимаются при проверке в течение grace-периода после ротации
+type KeySet struct {
+	mu    sync.RWMutex
+	keys  []*signingKey // keys[0] — активный ключ
+	grace time.Duration
+	// dir — каталог, в котором сохраняются ключи; пустой у набора в памяти
+	dir string
+}
+
+// NewKeySet создаёт набор с начальным ключом; grace должен быть не меньше
+// времени жизни токенов, иначе выданные до ротации токены перестанут проверяться
+func NewKeySet(initial ed25519.PrivateKey, grace time.Duration) *KeySet {
+	return &KeySet{
+		keys:  []*signingKey{newSigningKey(initial)},
+		grace: grace,
+	}
+}
+
+// OpenKeyDir загружает ключи из каталога dir: самый новый становится активным, более
+// старые принимаются до конца grace-периода после появления следующего. Ротированные
+// ключи сохраняются в dir, поэтому после перезапуска выданные токены продолжают проверяться.
+// Если в каталоге нет действующих ключей, создаётся новый.
+func OpenKeyDir(dir string, grace time.Duration) (*KeySet, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create key directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read key directory: %w", err)
+	}
+
+	ks := &KeySet{grace: grace, dir: dir}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".pem")
+		if !ok || e.IsDir() {
+			continue
+		}
+		nanos, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: file name must be a creation time in unix nanoseconds", e.Name())
+		}
+		key, err := LoadKey(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		k := newSigningKey(key)
+		k.createdAt = time.Unix(0, nanos)
+		ks.keys = append(ks.keys, k)
+	}
+	slices.SortFunc(ks.keys, func(a, b *signingKey) int { return b.createdAt.Compare(a.createdAt) })
+	for i := 1; i < len(ks.keys); i++ {
+		ks.keys[i].retireAt = ks.keys[i-1].createdAt.Add(grace)
+	}
+	ks.pruneLocked(time.Now())
+
+	if len(ks.keys) == 0 {
+		key, err := GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		k := newSigningKey(key)
+		if err := ks.saveKey(k); err != nil {
+			return nil, err
+		}
+		ks.keys = []*signingKey{k}
+	}
+	return ks, nil
+}
+
+func newSigningKey(key ed25519.PrivateKey) *signingKey {
+	return &signingKey{
+		id:        thumbprint(key.Public().(ed25519.PublicKey)),
+		private:   key,
+		createdAt: time.Now(),
+	}
+}
+
+func (ks *KeySet) keyPath(k *signingKey) string {
+	return filepath.Join(ks.dir, strconv.FormatInt(k.createdAt.UnixNano(), 10)+".pem")
+}
+
+// saveKey записывает ключ в каталог набора через временный файл, чтобы при сбое
+// не остался обрезанный PEM
+func (ks *KeySet) saveKey(k *signingKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return fmt.Errorf("encode signing key: %w", err)
+	}
+	path := ks.keyPath(k)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return fmt.Errorf("write signing key: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write signing key: %w", err)
+	}
+	return nil
+}
+
+// thumbprint вычисляет kid как JWK thumbprint (RFC 7638)
+func thumbprint(pub ed25519.PublicKey) string {
+	x := base64.RawURLEncoding.EncodeToString(pub)
+	sum := sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + x + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Rotate делает новый ключ активным; прежние ключи остаются для проверки до истечения grace-периода
+func (ks *KeySet) Rotate() (string, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	next := newSigningKey(key)
+	// Ключ сохраняется до того, как им начнут подписывать токены
+	if ks.dir != "" {
+		if err := ks.saveKey(next); err != nil {
+			return "", err
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	now := time.Now()
+	for _, k := range ks.keys {
+		if k.retireAt.IsZero() {
+			k.retireAt = now.Add(ks.grace)
+		}
+	}
+	ks.keys = append([]*signingKey{next}, ks.keys...)
+	ks.pruneLocked(now)
+	return next.id, nil
+}
+
+// Run ротирует ключи с заданным интервалом, пока не отменён ctx. Интервал отсчитывается
+// от создания активного ключа, а не от запуска процесса: иначе сервис, который
+// перезапускают чаще interval, никогда не сменил бы ключ. Если срок уже прошёл,
+// ключ меняется сразу.
+func (ks *KeySet) Run(ctx context.Context, interval time.Duration, onRotate func(kid string, err error)) {
+	timer := time.NewTimer(ks.untilRotation(interval, time.Now()))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			kid, err := ks.Rotate()
+			if onRotate != nil {
+				onRotate(kid, err)
+			}
+			next := ks.untilRotation(interval, time.Now())
+			if err != nil {
+				// Активный ключ не сменился, и его срок уже прошёл: повторяем не сразу
+				next = min(interval, time.Minute)
+			}
+			timer.Reset(next)
+		}
+	}
+}
+
+// untilRotation возвращает, сколько осталось до ротации активного ключа на момент now
+func (ks *KeySet) untilRotation(interval time.Duration, now time.Time) time.Duration {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return max(ks.keys[0].createdAt.Add(interval).Sub(now), 0)
+}
+
+// pruneLocked удаляет ключи, у которых закончился grace-период, вместе с их файлами;
+// вызывается под ks.mu
+func (ks *KeySet) pruneLocked(now time.Time) {
+	kept := ks.keys[:0]
+	for _, k := range ks.keys {
+		if k.retireAt.IsZero() || now.Before(k.retireAt) {
+			kept = append(kept, k)
+		} else if ks.dir != "" {
+			os.Remove(ks.keyPath(k))
+		}
+	}
+	ks.keys = kept
+}
+
+func (ks *KeySet) signingKey() (string, ed25519.PrivateKey) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.keys[0].id, ks.keys[0].private
+}
+
+func (ks *KeySet) verificationKey(kid string) (ed25519.PublicKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	now := time.Now()
+	for _, k := range ks.keys {
+		if k.id == kid && (k.retireAt.IsZero() || now.Before(k.retireAt)) {
+			return k.private.Public().(ed25519.PublicKey), true
+		}
+	}
+	return nil, false
+}
+
+// PublicKeys возвращает открытые ключи, которыми может быть подписан действующий токен
+func (ks *KeySet) PublicKeys() []JWK {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	now := time.Now()
+	jwks := make([]JWK, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		if !k.retireAt.IsZero() && !now.Before(k.retireAt) {
+			continue
+		}
+		jwks = append(jwks, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k.private.Public().(ed25519.PublicKey)),
+			Kid: k.id,
+			Alg: "EdDSA",
+			Use: "sig",
+		})
+	}
+	return jwks
+}
+
+// GenerateKey создаёт новый ключ подписи Ed25519
+func GenerateKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate signing key: %w", err)
+	}
+	return key, nil
+}
+
+// LoadKey читает ключ подписи Ed25519 из PEM-файла в формате PKCS#8
+// (openssl genpkey -algorithm ed25519)
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing key: no PEM block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key: not an Ed25519 key")
+	}
+	return key, nil
+}
@@ -0,0 +1,141 @@
+package token
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestOpenKeyDirKeepsRotatedKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	ks, err := OpenKeyDir(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("OpenKeyDir: %v", err)
+	}
+	m := NewManager(ks, "auth", "tasks", time.Minute)
+	before, _, err := m.Issue("alice", "s1", nil, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if _, err := ks.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	after, _, err := m.Issue("alice", "s1", nil, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// Перезапуск: ключи читаются из каталога, активным остаётся ротированный
+	reopened, err := OpenKeyDir(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	m2 := NewManager(reopened, "auth", "tasks", time.Minute)
+	for name, raw := range map[string]string{"before rotation": before, "after rotation": after} {
+		if _, err := m2.Parse(raw); err != nil {
+			t.Errorf("token issued %s: %v", name, err)
+		}
+	}
+	activeBefore, _ := ks.signingKey()
+	activeAfter, _ := reopened.signingKey()
+	if activeBefore != activeAfter {
+		t.Errorf("active key after reopen = %s, want %s", activeAfter, activeBefore)
+	}
+	if got := len(reopened.PublicKeys()); got != 2 {
+		t.Errorf("published keys = %d, want 2", got)
+	}
+}
+
+func TestOpenKeyDirDropsRetiredKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	ks, err := OpenKeyDir(dir, 0)
+	if err != nil {
+		t.Fatalf("OpenKeyDir: %v", err)
+	}
+	if _, err := ks.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("key files after rotation with zero grace = %d, want 1", len(files))
+	}
+}
+
+func TestOpenKeyDirRejectsUnknownFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "signing.pem"), []byte("junk"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenKeyDir(dir, time.Hour); err == nil {
+		t.Fatal("OpenKeyDir accepted a key file without a creation time")
+	}
+}
+
+func TestRunRotatesFromActiveKeyAge(t *testing.T) {
+	tests := []struct {
+		name string
+		// age — возраст активного ключа при запуске Run
+		age         time.Duration
+		wantRotated bool
+	}{
+		// Перезапуск не сбрасывает отсчёт: просроченный ключ меняется сразу
+		{"overdue key", 2 * time.Hour, true},
+		{"key due soon", time.Hour - 20*time.Millisecond, true},
+		{"fresh key", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ks, err := OpenKeyDir(dir, time.Hour)
+			if err != nil {
+				t.Fatalf("OpenKeyDir: %v", err)
+			}
+			// Ключ, созданный age назад прошлым запуском сервиса
+			files, _ := filepath.Glob(filepath.Join(dir, "*.pem"))
+			created := time.Now().Add(-tt.age)
+			if err := os.Rename(files[0], filepath.Join(dir, strconv.FormatInt(created.UnixNano(), 10)+".pem")); err != nil {
+				t.Fatal(err)
+			}
+			if ks, err = OpenKeyDir(dir, time.Hour); err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			before, _ := ks.signingKey()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			rotated := make(chan string, 1)
+			go ks.Run(ctx, time.Hour, func(kid string, err error) {
+				if err != nil {
+					t.Errorf("rotation failed: %v", err)
+				}
+				select {
+				case rotated <- kid:
+				default:
+				}
+			})
+
+			select {
+			case kid := <-rotated:
+				if !tt.wantRotated {
+					t.Fatalf("fresh key was rotated to %s", kid)
+				}
+				if active, _ := ks.signingKey(); active != kid || kid == before {
+					t.Fatalf("active key = %s, rotated %s, before %s", active, kid, before)
+				}
+			case <-ctx.Done():
+				if tt.wantRotated {
+					t.Fatal("key was not rotated")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// Claims — полезная нагрузка access-токена
+type Claims struct {
+	jwt.RegisteredClaims
+	// SessionID связывает access-токен с сессией (цепочкой refresh-токенов)
+	SessionID string `json:"sid,omitempty"`
+	// Roles — роли пользователя на момент выпуска токена
+	Roles []string `json:"roles,omitempty"`
+	// Scope — разрешения через пробел, как в RFC 9068
+	Scope string `json:"scope,omitempty"`
+}
+
+// Scopes возвращает разрешения токена списком
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// Manager выпускает и проверяет подписанные EdDSA (Ed25519) JWT.
+// Токены подписываются текущим активным ключом, проверяются любым ключом из набора.
+type Manager struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+func NewManager(keys *KeySet, issuer, audience string, ttl time.Duration) *Manager {
+	return &Manager{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		ttl:      ttl,
+	}
+}
+
+// Keys возвращает набор ключей подписи
+func (m *Manager) Keys() *KeySet {
+	return m.keys
+}
+
+// TTL возвращает время жизни выпускаемых access-токенов
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+// Issue выпускает access-токен для subject в рамках сессии sessionID
+func (m *Manager) Issue(subject, sessionID string, roles, scopes []string) (string, *Claims, error) {
+	now := time.Now()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    m.issuer,
+			Audience:  jwt.ClaimStrings{m.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+			ID:        uuid.NewString(),
+		},
+		SessionID: sessionID,
+		Roles:     roles,
+		Scope:     strings.Join(scopes, " "),
+	}
+
+	kid, key := m.keys.signingKey()
+	t := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	t.Header["kid"] = kid
+	signed, err := t.SignedString(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("sign token: %w", err)
+	}
+	return signed, claims, nil
+}
+
+// Parse проверяет подпись, срок действия, издателя и аудиторию токена
+func (m *Manager) Parse(raw string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := m.keys.verificationKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithAudience(m.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, ErrInvalidToken
+	}
+	if claims.Subject == "" || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,206 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(NewKeySet(key, time.Minute), "auth", "tasks", time.Minute)
+}
+
+// validClaims — утверждения, которые Manager принял бы; случаи теста портят одно из них
+func validClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			Issuer:    "auth",
+			Audience:  jwt.ClaimStrings{"tasks"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			ID:        "jti-1",
+		},
+		SessionID: "s1",
+	}
+}
+
+// signWith подписывает claims активным ключом m, как это делает Issue
+func signWith(t *testing.T, m *Manager, claims *Claims) string {
+	t.Helper()
+	kid, key := m.keys.signingKey()
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	tok.Header["kid"] = kid
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func TestManagerParse(t *testing.T) {
+	m := newTestManager(t)
+	other := newTestManager(t)
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantErr error
+	}{
+		{
+			name: "issued token",
+			token: func(t *testing.T) string {
+				raw, _, err := m.Issue("alice", "s1", []string{"user"}, []string{"tasks:read"})
+				if err != nil {
+					t.Fatalf("Issue: %v", err)
+				}
+				return raw
+			},
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return signWith(t, m, c)
+			},
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name: "without expiration",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.ExpiresAt = nil
+				return signWith(t, m, c)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "wrong audience",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Audience = jwt.ClaimStrings{"billing"}
+				return signWith(t, m, c)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Issuer = "evil"
+				return signWith(t, m, c)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "without subject",
+			token: func(t *testing.T) string {
+				c := validClaims()
+				c.Subject = ""
+				return signWith(t, m, c)
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "tampered signature",
+			token: func(t *testing.T) string {
+				raw := signWith(t, m, validClaims())
+				i := strings.LastIndex(raw, ".") + 1
+				flipped := byte('A')
+				if raw[i] == 'A' {
+					flipped = 'B'
+				}
+				return raw[:i] + string(flipped) + raw[i+1:]
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "tampered payload",
+			token: func(t *testing.T) string {
+				parts := strings.Split(signWith(t, m, validClaims()), ".")
+				c := validClaims()
+				c.Subject = "admin"
+				forged := strings.Split(signWith(t, m, c), ".")
+				return parts[0] + "." + forged[1] + "." + parts[2]
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "unknown kid",
+			token: func(t *testing.T) string {
+				return signWith(t, other, validClaims())
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "known kid, foreign key",
+			token: func(t *testing.T) string {
+				kid, _ := m.keys.signingKey()
+				_, key := other.keys.signingKey()
+				tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, validClaims())
+				tok.Header["kid"] = kid
+				raw, err := tok.SignedString(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			// Открытый ключ как секрет HMAC — классическая подмена алгоритма
+			name: "HS256 with public key",
+			token: func(t *testing.T) string {
+				kid, key := m.keys.signingKey()
+				tok := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+				tok.Header["kid"] = kid
+				raw, err := tok.SignedString([]byte(key.Public().(ed25519.PublicKey)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name: "alg none",
+			token: func(t *testing.T) string {
+				tok := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims())
+				raw, err := tok.SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+			wantErr: ErrInvalidToken,
+		},
+		{
+			name:    "garbage",
+			token:   func(t *testing.T) string { return "not.a.token" },
+			wantErr: ErrInvalidToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := m.Parse(tt.token(t))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (claims.Subject != "alice" || claims.SessionID != "s1") {
+				t.Fatalf("Parse claims = %+v", claims)
+			}
+		})
+	}
+}
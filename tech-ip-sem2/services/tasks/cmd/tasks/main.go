@@ -1,53 +1,241 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
+	// База часовых поясов встроена в бинарник, чтобы X-Timezone работал и без tzdata в системе
+	_ "time/tzdata"
 
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	pb "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/proto/tasks"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/logger"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+	grp "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/grpc"
 	handlers "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/http"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/idempotency"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
 )
 
 func main() {
 	// Инициализация структурированного логгера
 	logrusLogger := logger.Init("tasks")
 
+	// Подкоманда управления схемой базы: tasks migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(context.Background(), os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := run(logrusLogger); err != nil {
+		logrusLogger.WithError(err).Error("Tasks service failed")
+		os.Exit(1)
+	}
+}
+
+// run запускает серверы и работает до SIGINT/SIGTERM или ошибки одного из серверов.
+// Ошибки возвращаются, а не завершают процесс, чтобы отложенные вызовы успели закрыть хранилище.
+func run(logrusLogger *logrus.Logger) error {
 	tasksPort := os.Getenv("TASKS_PORT")
 	if tasksPort == "" {
 		tasksPort = "8082"
 	}
+	tasksGrpcPort := os.Getenv("TASKS_GRPC_PORT")
+	if tasksGrpcPort == "" {
+		tasksGrpcPort = "50052"
+	}
 	authGrpcAddr := os.Getenv("AUTH_GRPC_ADDR")
 	if authGrpcAddr == "" {
 		authGrpcAddr = "localhost:50051"
 	}
+	idempotencyTTL, err := durationFromEnv("TASKS_IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return err
+	}
+	outboxInterval, err := durationFromEnv("TASKS_OUTBOX_INTERVAL", time.Second)
+	if err == nil && outboxInterval <= 0 {
+		err = errors.New("TASKS_OUTBOX_INTERVAL must be positive")
+	}
+	if err != nil {
+		return err
+	}
+	// Адреса вебхуков во внутренних сетях разрешаются только для локальной разработки
+	webhookAllowPrivate := false
+	if v := os.Getenv("TASKS_WEBHOOK_ALLOW_PRIVATE"); v != "" {
+		if webhookAllowPrivate, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("invalid TASKS_WEBHOOK_ALLOW_PRIVATE: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	authClient, err := authclient.NewClient(authGrpcAddr, 2*time.Second, logrusLogger)
 	if err != nil {
-		logrusLogger.WithError(err).Fatal("Failed to create auth client")
+		return fmt.Errorf("create auth client: %w", err)
 	}
 	defer authClient.Close()
 
-	taskService := service.NewTaskService()
-	taskHandler := handlers.NewTaskHandler(taskService, authClient, logrusLogger)
+	// Фоновые задачи работают с хранилищем, поэтому останавливаются до его закрытия
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	goWorker := func(fn func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			fn(workersCtx)
+		}()
+	}
+
+	repo, webhookStore, closeRepo, err := openRepository(workersCtx, logrusLogger)
+	if err != nil {
+		return fmt.Errorf("open task storage: %w", err)
+	}
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+		if err := closeRepo(); err != nil {
+			logrusLogger.WithError(err).Error("failed to close task storage")
+			return
+		}
+		logrusLogger.Info("Task storage closed")
+	}()
+
+	idempotencyStore := idempotency.NewStore(idempotencyTTL)
+	goWorker(func(ctx context.Context) { pruneIdempotencyKeys(ctx, idempotencyStore, logrusLogger) })
+
+	webhookGuard := webhook.Guard{AllowPrivate: webhookAllowPrivate}
+	if webhookAllowPrivate {
+		logrusLogger.Warn("TASKS_WEBHOOK_ALLOW_PRIVATE is set: webhooks may target internal networks")
+	}
+	webhookClient := &http.Client{
+		Transport: webhookGuard.Transport(),
+		Timeout:   10 * time.Second,
+		// Перенаправления не выполняются: подписанное тело уходит только на зарегистрированный адрес
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	webhooks := webhook.NewService(webhookStore, webhookGuard, webhookClient, webhook.DefaultRetryPolicy, logrusLogger)
+	// Очередь доставок проверяется с той же периодичностью, что и outbox
+	goWorker(func(ctx context.Context) { webhooks.Run(ctx, outboxInterval) })
+
+	events := service.NewEventBus(service.DefaultEventHistory)
+	taskService := service.NewTaskService(repo, service.UUIDv7IDs, events, webhooks)
+	goWorker(func(ctx context.Context) {
+		taskService.RunOutbox(ctx, outboxInterval, func(n int, err error) {
+			if err != nil {
+				logrusLogger.WithError(err).Error("failed to dispatch task events from outbox")
+				return
+			}
+			logrusLogger.WithField("count", n).Debug("task events dispatched from outbox")
+		})
+	})
+
+	taskHandler := handlers.NewTaskHandler(taskService, authClient, idempotencyStore, webhooks, logrusLogger)
+
+	lis, err := net.Listen("tcp", ":"+tasksGrpcPort)
+	if err != nil {
+		return fmt.Errorf("listen grpc: %w", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterTaskServiceServer(grpcServer, grp.NewServer(taskService, authClient, logrusLogger))
+	reflection.Register(grpcServer)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /v1/tasks", taskHandler.CreateTask)
-	mux.HandleFunc("GET /v1/tasks", taskHandler.ListTasks)
-	mux.HandleFunc("GET /v1/tasks/{id}", taskHandler.GetTask)
-	mux.HandleFunc("PATCH /v1/tasks/{id}", taskHandler.UpdateTask)
-	mux.HandleFunc("DELETE /v1/tasks/{id}", taskHandler.DeleteTask)
+	taskHandler.Routes(mux)
 
 	// RequestIDMiddleware должен идти первым
-	handler := middleware.RequestIDMiddleware(middleware.LoggingMiddleware(mux))
+	httpServer := &http.Server{
+		Addr:              ":" + tasksPort,
+		Handler:           middleware.RequestIDMiddleware(middleware.LoggingMiddleware(mux)),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		logrusLogger.WithField("port", tasksGrpcPort).Info("Tasks gRPC server starting")
+		if err := grpcServer.Serve(lis); err != nil {
+			serveErr <- fmt.Errorf("serve grpc: %w", err)
+		}
+	}()
+	go func() {
+		logrusLogger.WithField("port", tasksPort).Info("Tasks service starting")
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- fmt.Errorf("serve http: %w", err)
+		}
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logrusLogger.Info("Shutting down Tasks servers...")
+	case runErr = <-serveErr:
+	}
+	shutdownServers(httpServer, grpcServer, 10*time.Second, logrusLogger)
+	return runErr
+}
+
+// shutdownServers останавливает оба сервера параллельно, дожидаясь завершения текущих запросов.
+// Открытые потоки событий (SSE и gRPC Watch) сами не завершаются, поэтому по истечении
+// timeout соединения закрываются принудительно; клиенты переподключатся с последнего события.
+func shutdownServers(httpServer *http.Server, grpcServer *grpc.Server, timeout time.Duration, logger *logrus.Logger) {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.WithError(err).Warn("http server shutdown timed out, closing connections")
+			httpServer.Close()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			logger.Warn("grpc server shutdown timed out, closing connections")
+			grpcServer.Stop()
+		}
+	}()
+	wg.Wait()
+}
 
-	addr := fmt.Sprintf(":%s", tasksPort)
-	logrusLogger.WithField("port", tasksPort).Info("Tasks service starting")
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		logrusLogger.WithError(err).Fatal("server failed")
+// pruneIdempotencyKeys периодически удаляет истёкшие ключи идемпотентности
+func pruneIdempotencyKeys(ctx context.Context, store *idempotency.Store, logger *logrus.Logger) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := store.PruneExpired(now); n > 0 {
+				logger.WithField("count", n).Debug("expired idempotency keys pruned")
+			}
+		}
 	}
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/migrate"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/postgres"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/sqlite"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/wal"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
+)
+
+const migrateUsage = "usage: tasks migrate up | down | status"
+
+// database — открытая SQL-база вместе с мигратором и репозиториями
+type database struct {
+	db       *sql.DB
+	migrator *migrate.Migrator
+	repo     service.TaskRepository
+	webhooks webhook.Store
+}
+
+func storageBackend() string {
+	if backend := os.Getenv("TASKS_STORAGE"); backend != "" {
+		return backend
+	}
+	return "memory"
+}
+
+// openDatabase подключается к SQL-хранилищу, выбранному в TASKS_STORAGE (sqlite | postgres)
+func openDatabase(ctx context.Context, backend string) (*database, error) {
+	var (
+		db      *sql.DB
+		newMig  func(*sql.DB) (*migrate.Migrator, error)
+		newRepo func(*sql.DB) service.TaskRepository
+		newHook func(*sql.DB) webhook.Store
+		err     error
+	)
+
+	switch backend {
+	case "sqlite":
+		path := os.Getenv("TASKS_SQLITE_PATH")
+		if path == "" {
+			path = "tasks.db"
+		}
+		db, err = sqlite.Open(path)
+		newMig = sqlite.NewMigrator
+		newRepo = func(db *sql.DB) service.TaskRepository { return sqlite.NewTaskRepository(db) }
+		newHook = func(db *sql.DB) webhook.Store { return sqlite.NewWebhookRepository(db) }
+	case "postgres":
+		dsn := os.Getenv("TASKS_POSTGRES_DSN")
+		if dsn == "" {
+			return nil, errors.New("TASKS_POSTGRES_DSN is required for postgres storage")
+		}
+		db, err = postgres.Open(ctx, dsn)
+		newMig = postgres.NewMigrator
+		newRepo = func(db *sql.DB) service.TaskRepository { return postgres.NewTaskRepository(db) }
+		newHook = func(db *sql.DB) webhook.Store { return postgres.NewWebhookRepository(db) }
+	default:
+		return nil, fmt.Errorf("unknown TASKS_STORAGE %q", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := newMig(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &database{db: db, migrator: migrator, repo: newRepo(db), webhooks: newHook(db)}, nil
+}
+
+// openRepository выбирает хранилище задач и вебхуков по TASKS_STORAGE (memory | sqlite | postgres)
+// и для SQL-хранилищ накатывает миграции схемы. Возвращаемая функция закрывает соединение с базой.
+func openRepository(ctx context.Context, log *logrus.Logger) (service.TaskRepository, webhook.Store, func() error, error) {
+	backend := storageBackend()
+	if backend == "memory" {
+		// Журнал TASKS_WAL_DIR сохраняет только задачи
+		log.Warn("TASKS_STORAGE=memory: webhooks and their delivery log are lost on restart")
+		repo, closeRepo, err := openMemoryRepository(ctx, log)
+		return repo, webhook.NewMemoryStore(), closeRepo, err
+	}
+
+	d, err := openDatabase(ctx, backend)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	applied, err := d.migrator.Up(ctx)
+	if err != nil {
+		d.db.Close()
+		return nil, nil, nil, fmt.Errorf("migrate: %w", err)
+	}
+	for _, m := range applied {
+		log.WithFields(logrus.Fields{"version": m.Version, "name": m.Name}).Info("migration applied")
+	}
+	log.WithField("storage", backend).Info("Using SQL task storage")
+	return d.repo, d.webhooks, d.db.Close, nil
+}
+
+// openMemoryRepository возвращает хранилище в памяти. Если задан TASKS_WAL_DIR,
+// изменения журналируются на диск и восстанавливаются при старте.
+func openMemoryRepository(ctx context.Context, log *logrus.Logger) (service.TaskRepository, func() error, error) {
+	dir := os.Getenv("TASKS_WAL_DIR")
+	if dir == "" {
+		log.Warn("TASKS_STORAGE=memory without TASKS_WAL_DIR: tasks are lost on restart")
+		return service.NewMemoryTaskRepository(), func() error { return nil }, nil
+	}
+
+	interval, err := durationFromEnv("TASKS_WAL_COMPACT_INTERVAL", 5*time.Minute)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repo, rec, err := wal.Open(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	entry := log.WithFields(logrus.Fields{
+		"dir":            dir,
+		"snapshot_tasks": rec.SnapshotTasks,
+		"replayed":       rec.Replayed,
+	})
+	if rec.TruncatedBytes > 0 {
+		entry.WithField("truncated_bytes", rec.TruncatedBytes).Warn("incomplete journal tail discarded")
+	}
+	entry.Info("Using journaled in-memory task storage")
+
+	if interval <= 0 {
+		return repo, repo.Close, nil
+	}
+	compacting := make(chan struct{})
+	go func() {
+		defer close(compacting)
+		repo.Run(ctx, interval, func(n int, err error) {
+			if err != nil {
+				log.WithError(err).Error("journal compaction failed")
+				return
+			}
+			log.WithField("records", n).Debug("journal compacted")
+		})
+	}()
+	// Журнал закрывается после остановки фонового сжатия; к этому моменту ctx уже отменён
+	return repo, func() error {
+		<-compacting
+		return repo.Close()
+	}, nil
+}
+
+func durationFromEnv(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// runMigrateCommand управляет схемой базы вручную: tasks migrate up|down|status
+func runMigrateCommand(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+	backend := storageBackend()
+	if backend == "memory" {
+		return errors.New("migrations require TASKS_STORAGE=sqlite or postgres")
+	}
+
+	d, err := openDatabase(ctx, backend)
+	if err != nil {
+		return err
+	}
+	defer d.db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := d.migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		m, ok, err := d.migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("no migrations to revert")
+			return nil
+		}
+		fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+	case "status":
+		statuses, err := d.migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
@@ -14,6 +14,35 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// Scopes, которые Tasks service требует от токена
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeTasksAdmin = "tasks:admin"
+)
+
+// Identity — владелец проверенного токена и его разрешения
+type Identity struct {
+	Subject string
+	Roles   []string
+	Scopes  []string
+}
+
+// HasScope сообщает, есть ли у владельца токена указанное разрешение
+func (i Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier проверяет access-токен; его реализует Client
+type Verifier interface {
+	VerifyToken(ctx context.Context, token string) (bool, Identity, error)
+}
+
 type Client struct {
 	conn    *grpc.ClientConn
 	client  pb.AuthServiceClient
@@ -39,7 +68,7 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
-func (c *Client) VerifyToken(ctx context.Context, token string) (bool, string, error) {
+func (c *Client) VerifyToken(ctx context.Context, token string) (bool, Identity, error) {
 	// Извлекаем request-id из контекста для прокидывания в gRPC метаданные
 	requestID := middleware.GetRequestID(ctx)
 
@@ -64,29 +93,34 @@ func (c *Client) VerifyToken(ctx context.Context, token string) (bool, string, e
 		st, ok := status.FromError(err)
 		if !ok {
 			logEntry.WithError(err).Error("auth service unavailable")
-			return false, "", fmt.Errorf("auth service unavailable: %w", err)
+			return false, Identity{}, fmt.Errorf("auth service unavailable: %w", err)
 		}
 
 		switch st.Code() {
 		case codes.Unauthenticated:
 			logEntry.WithField("token_present", token != "").Debug("token invalid")
-			return false, "", nil
+			return false, Identity{}, nil
 		case codes.DeadlineExceeded:
 			logEntry.Warn("auth service timeout")
-			return false, "", fmt.Errorf("auth service timeout")
+			return false, Identity{}, fmt.Errorf("auth service timeout")
 		default:
 			logEntry.WithFields(logrus.Fields{
 				"code":  st.Code(),
 				"error": st.Message(),
 			}).Error("auth service error")
-			return false, "", fmt.Errorf("auth service error: %v", st.Message())
+			return false, Identity{}, fmt.Errorf("auth service error: %v", st.Message())
 		}
 	}
 
 	logEntry.WithFields(logrus.Fields{
 		"valid":   resp.Valid,
 		"subject": resp.Subject,
+		"scopes":  resp.Scopes,
 	}).Debug("auth response received")
 
-	return resp.Valid, resp.Subject, nil
+	return resp.Valid, Identity{
+		Subject: resp.Subject,
+		Roles:   resp.Roles,
+		Scopes:  resp.Scopes,
+	}, nil
 }
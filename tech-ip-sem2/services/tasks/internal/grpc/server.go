@@ -0,0 +1,382 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	pb "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/proto/tasks"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server — gRPC API задач поверх того же TaskService, что и REST API
+type Server struct {
+	pb.UnimplementedTaskServiceServer
+	taskService *service.TaskService
+	authClient  authclient.Verifier
+	logger      *logrus.Logger
+}
+
+func NewServer(ts *service.TaskService, ac authclient.Verifier, logger *logrus.Logger) *Server {
+	return &Server{
+		taskService: ts,
+		authClient:  ac,
+		logger:      logger,
+	}
+}
+
+// withRequestID берёт request-id из входящих метаданных (или создаёт новый) и кладёт
+// его в контекст, откуда его прочитают логи и клиент Auth service
+func withRequestID(ctx context.Context) (context.Context, string) {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-request-id"); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	return context.WithValue(ctx, middleware.RequestIDKey, requestID), requestID
+}
+
+// start готовит контекст и запись лога для вызова метода
+func (s *Server) start(ctx context.Context, method string) (context.Context, *logrus.Entry) {
+	ctx, requestID := withRequestID(ctx)
+	return ctx, s.logger.WithFields(logrus.Fields{
+		"component":  "grpc_server",
+		"method":     method,
+		"request_id": requestID,
+	})
+}
+
+// authenticate проверяет токен из метаданных authorization через Auth service
+// и наличие у его владельца разрешения scope. Возвращаемый контекст для владельца
+// scope tasks:admin помечен как административный.
+func (s *Server) authenticate(ctx context.Context, logEntry *logrus.Entry, scope string) (context.Context, authclient.Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		logEntry.Warn("missing authorization metadata")
+		return ctx, authclient.Identity{}, status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		logEntry.Warn("invalid authorization metadata format")
+		return ctx, authclient.Identity{}, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+
+	valid, identity, err := s.authClient.VerifyToken(ctx, token)
+	if err != nil {
+		logEntry.WithError(err).Error("authentication service unavailable")
+		return ctx, authclient.Identity{}, status.Error(codes.Unavailable, "authentication service unavailable")
+	}
+	if !valid {
+		logEntry.WithField("token_present", token != "").Warn("invalid token")
+		return ctx, authclient.Identity{}, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if !identity.HasScope(scope) {
+		logEntry.WithFields(logrus.Fields{
+			"subject": identity.Subject,
+			"scope":   scope,
+		}).Warn("insufficient scope")
+		return ctx, authclient.Identity{}, status.Error(codes.PermissionDenied, "insufficient scope")
+	}
+	if identity.HasScope(authclient.ScopeTasksAdmin) {
+		ctx = service.WithAdmin(ctx)
+	}
+	return ctx, identity, nil
+}
+
+// taskError переводит ошибки TaskService в статусы gRPC
+func taskError(logEntry *logrus.Entry, err error) error {
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		logEntry.Warn("task not found")
+		return status.Error(codes.NotFound, "task not found")
+	case errors.Is(err, service.ErrForbidden):
+		logEntry.Warn("access to task denied")
+		return status.Error(codes.PermissionDenied, "forbidden")
+	case errors.Is(err, service.ErrPreconditionFailed):
+		logEntry.Warn("task version does not match expected_version")
+		return status.Error(codes.FailedPrecondition, "precondition failed")
+	case errors.Is(err, service.ErrVersionConflict):
+		logEntry.Warn("concurrent task modification")
+		return status.Error(codes.Aborted, "task was modified concurrently")
+	case errors.Is(err, service.ErrTaskExists):
+		logEntry.WithError(err).Error("task id collision")
+		return status.Error(codes.AlreadyExists, "task already exists")
+	case errors.Is(err, service.ErrTagNotFound):
+		logEntry.Warn("tag not found")
+		return status.Error(codes.NotFound, "tag not found")
+	case errors.Is(err, service.ErrTagExists):
+		logEntry.WithError(err).Warn("tag already exists")
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrTitleRequired),
+		errors.Is(err, service.ErrInvalidDueDate),
+		errors.Is(err, service.ErrInvalidTag),
+		errors.Is(err, service.ErrInvalidQuery),
+		errors.Is(err, service.ErrInvalidCursor):
+		logEntry.WithError(err).Warn("invalid argument")
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		logEntry.WithError(err).Error("task operation failed")
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func (s *Server) Create(ctx context.Context, req *pb.CreateTaskRequest) (*pb.Task, error) {
+	ctx, logEntry := s.start(ctx, "Create")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	dueDate, err := parseDueDate(req.DueDate)
+	if err != nil {
+		return nil, taskError(logEntry, err)
+	}
+	created, err := s.taskService.Create(ctx, service.Task{
+		Owner:       identity.Subject,
+		Title:       req.Title,
+		Description: req.Description,
+		DueDate:     dueDate,
+		Tags:        req.Tags,
+	})
+	if err != nil {
+		return nil, taskError(logEntry, err)
+	}
+
+	logEntry.WithField("task_id", created.ID).Info("task created successfully")
+	return toProtoTask(created), nil
+}
+
+func (s *Server) Get(ctx context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
+	ctx, logEntry := s.start(ctx, "Get")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksRead)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.taskService.Get(ctx, identity.Subject, req.Id)
+	if err != nil {
+		return nil, taskError(logEntry.WithField("task_id", req.Id), err)
+	}
+	return toProtoTask(task), nil
+}
+
+func (s *Server) List(ctx context.Context, req *pb.ListTasksRequest) (*pb.ListTasksResponse, error) {
+	ctx, logEntry := s.start(ctx, "List")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksRead)
+	if err != nil {
+		return nil, err
+	}
+
+	q := service.ListQuery{
+		Done:   req.Done,
+		Q:      req.Q,
+		Sort:   req.Sort,
+		Limit:  int(req.Limit),
+		Cursor: req.Cursor,
+		Tags:   req.Tags,
+		AnyTag: req.AnyTag,
+	}
+	if req.DueBefore != "" {
+		due, err := service.ParseDueDate(req.DueBefore)
+		if err != nil {
+			return nil, taskError(logEntry, err)
+		}
+		q.DueBefore = due.Time
+	}
+	if req.DueAfter != "" {
+		due, err := service.ParseDueDate(req.DueAfter)
+		if err != nil {
+			return nil, taskError(logEntry, err)
+		}
+		q.DueAfter = due.Time
+	}
+
+	page, err := s.taskService.List(ctx, identity.Subject, q)
+	if err != nil {
+		return nil, taskError(logEntry, err)
+	}
+
+	resp := &pb.ListTasksResponse{
+		Tasks:      make([]*pb.Task, 0, len(page.Tasks)),
+		NextCursor: page.NextCursor,
+	}
+	for _, t := range page.Tasks {
+		resp.Tasks = append(resp.Tasks, toProtoTask(t))
+	}
+	logEntry.WithField("count", len(resp.Tasks)).Debug("tasks listed")
+	return resp, nil
+}
+
+func (s *Server) Update(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.Task, error) {
+	ctx, logEntry := s.start(ctx, "Update")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksWrite)
+	if err != nil {
+		return nil, err
+	}
+	logEntry = logEntry.WithField("task_id", req.Id)
+
+	var dueDate service.DueDate
+	if req.DueDate != nil {
+		if dueDate, err = parseDueDate(*req.DueDate); err != nil {
+			return nil, taskError(logEntry, err)
+		}
+	}
+	task, err := s.taskService.Update(ctx, identity.Subject, req.Id, expectedVersion(req.ExpectedVersion),
+		func(t service.Task) (service.Task, error) {
+			if req.Title != nil {
+				t.Title = *req.Title
+			}
+			if req.Description != nil {
+				t.Description = *req.Description
+			}
+			if req.DueDate != nil {
+				t.DueDate = dueDate
+			}
+			if req.Done != nil {
+				t.Done = *req.Done
+			}
+			if req.Tags != nil {
+				t.Tags = req.Tags.Tags
+			}
+			return t, nil
+		})
+	if err != nil {
+		return nil, taskError(logEntry, err)
+	}
+
+	logEntry.Info("task updated successfully")
+	return toProtoTask(task), nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *pb.DeleteTaskRequest) (*pb.DeleteTaskResponse, error) {
+	ctx, logEntry := s.start(ctx, "Delete")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksWrite)
+	if err != nil {
+		return nil, err
+	}
+	logEntry = logEntry.WithField("task_id", req.Id)
+
+	if err := s.taskService.Delete(ctx, identity.Subject, req.Id, expectedVersion(req.ExpectedVersion)); err != nil {
+		return nil, taskError(logEntry, err)
+	}
+
+	logEntry.Info("task deleted successfully")
+	return &pb.DeleteTaskResponse{}, nil
+}
+
+// Watch передаёт изменения видимых вызывающему задач. С after_event_id поток
+// продолжается после этого события; если пропущенные события уже не сохранились,
+// возвращается OutOfRange — список задач нужно перечитать и подписаться заново.
+func (s *Server) Watch(req *pb.WatchRequest, stream pb.TaskService_WatchServer) error {
+	ctx, logEntry := s.start(stream.Context(), "Watch")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksRead)
+	if err != nil {
+		return err
+	}
+	var after uint64
+	if req.AfterEventId != "" {
+		if after, err = strconv.ParseUint(req.AfterEventId, 10, 64); err != nil {
+			logEntry.WithField("after_event_id", req.AfterEventId).Warn("invalid after_event_id")
+			return status.Error(codes.InvalidArgument, "invalid after_event_id")
+		}
+	}
+
+	sub, missed, resumed := s.taskService.Watch(identity.Subject, after)
+	defer sub.Close()
+
+	logEntry = logEntry.WithField("subject", identity.Subject)
+	if !resumed {
+		logEntry.WithField("after_event_id", after).Warn("requested events are no longer available")
+		return status.Error(codes.OutOfRange, "events after after_event_id are no longer available")
+	}
+	logEntry.WithField("missed", len(missed)).Info("watch started")
+
+	for _, ev := range missed {
+		if err := stream.Send(toProtoEvent(ev)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			logEntry.Debug("watch closed by client")
+			return status.FromContextError(ctx.Err()).Err()
+		case ev, ok := <-sub.Events():
+			if !ok {
+				logEntry.Warn("watcher fell behind")
+				return status.Error(codes.Aborted, "watcher fell behind, resume with after_event_id")
+			}
+			if err := stream.Send(toProtoEvent(ev)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// expectedVersion переводит expected_version в условие изменения; 0 — без условия
+func expectedVersion(v int64) service.IfMatch {
+	if v == 0 {
+		return nil
+	}
+	return service.IfMatch{v}
+}
+
+// parseDueDate разбирает необязательный срок; пустая строка — срок не задан
+func parseDueDate(raw string) (service.DueDate, error) {
+	if raw == "" {
+		return service.DueDate{}, nil
+	}
+	return service.ParseDueDate(raw)
+}
+
+func toProtoTask(t service.Task) *pb.Task {
+	task := &pb.Task{
+		Id:          t.ID,
+		Owner:       t.Owner,
+		Title:       t.Title,
+		Description: t.Description,
+		DueDate:     t.DueDate.String(),
+		Done:        t.Done,
+		Tags:        t.Tags,
+		Version:     t.Version,
+		CreatedAt:   timestamppb.New(t.CreatedAt),
+		UpdatedAt:   timestamppb.New(t.UpdatedAt),
+	}
+	if len(t.Shares) > 0 {
+		task.Shares = make(map[string]string, len(t.Shares))
+		for subject, perm := range t.Shares {
+			task.Shares[subject] = string(perm)
+		}
+	}
+	return task
+}
+
+var eventTypes = map[service.EventType]pb.TaskEvent_Type{
+	service.EventCreated: pb.TaskEvent_CREATED,
+	service.EventUpdated: pb.TaskEvent_UPDATED,
+	service.EventDeleted: pb.TaskEvent_DELETED,
+}
+
+func toProtoEvent(ev service.Event) *pb.TaskEvent {
+	return &pb.TaskEvent{
+		Id:         strconv.FormatUint(ev.ID, 10),
+		Type:       eventTypes[ev.Type],
+		Task:       toProtoTask(ev.Task),
+		OccurredAt: timestamppb.New(ev.OccurredAt),
+		Key:        ev.Key,
+	}
+}
@@ -0,0 +1,485 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	pb "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/proto/tasks"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeVerifier принимает только токены из заранее заданного набора
+type fakeVerifier map[string]authclient.Identity
+
+func (f fakeVerifier) VerifyToken(_ context.Context, token string) (bool, authclient.Identity, error) {
+	id, ok := f[token]
+	return ok, id, nil
+}
+
+// testClient — клиент gRPC API поверх хранилища в памяти с пользователями
+// alice и bob (чтение и запись) и reader (только чтение)
+type testClient struct {
+	pb.TaskServiceClient
+	tasks *service.TaskService
+}
+
+func newTestClient(t *testing.T) *testClient {
+	return newTestClientWith(t, service.UUIDv7IDs, service.DefaultEventHistory)
+}
+
+// newTestClientWith — testClient с генератором идентификаторов newID и историей событий из history записей
+func newTestClientWith(t *testing.T, newID service.IDGenerator, history int) *testClient {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	ts := service.NewTaskService(service.NewMemoryTaskRepository(), newID, service.NewEventBus(history))
+	write := []string{authclient.ScopeTasksRead, authclient.ScopeTasksWrite}
+	verifier := fakeVerifier{
+		"alice":  {Subject: "alice", Scopes: write},
+		"bob":    {Subject: "bob", Scopes: write},
+		"reader": {Subject: "reader", Scopes: []string{authclient.ScopeTasksRead}},
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := ggrpc.NewServer()
+	pb.RegisterTaskServiceServer(srv, NewServer(ts, verifier, logger))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := ggrpc.NewClient("passthrough:///bufnet",
+		ggrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		ggrpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{TaskServiceClient: pb.NewTaskServiceClient(conn), tasks: ts}
+}
+
+// as возвращает контекст вызова с токеном token в метаданных authorization
+func as(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// wantCode проверяет gRPC-код ошибки вызова
+func wantCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("code = %v (%v), want %v", got, err, want)
+	}
+}
+
+func TestCreateWithTakenIDReturnsAlreadyExists(t *testing.T) {
+	// Генератор всегда выдаёт один и тот же id: вторая задача не может его получить
+	c := newTestClientWith(t, func() (string, error) { return "t_1", nil }, service.DefaultEventHistory)
+	first, err := c.Create(as("alice"), &pb.CreateTaskRequest{Title: "первая"})
+	if err != nil || first.Id != "t_1" {
+		t.Fatalf("Create = %v, %v; want task t_1", first, err)
+	}
+	_, err = c.Create(as("bob"), &pb.CreateTaskRequest{Title: "вторая"})
+	wantCode(t, err, codes.AlreadyExists)
+}
+
+func TestAuthentication(t *testing.T) {
+	c := newTestClient(t)
+	task, err := c.Create(as("alice"), &pb.CreateTaskRequest{Title: "задача"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	withHeader := func(value string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", value)
+	}
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"missing metadata", func() error {
+			_, err := c.Get(context.Background(), &pb.GetTaskRequest{Id: task.Id})
+			return err
+		}, codes.Unauthenticated},
+		{"missing Bearer prefix", func() error {
+			_, err := c.Get(withHeader("alice"), &pb.GetTaskRequest{Id: task.Id})
+			return err
+		}, codes.Unauthenticated},
+		{"unknown token", func() error {
+			_, err := c.Get(as("mallory"), &pb.GetTaskRequest{Id: task.Id})
+			return err
+		}, codes.Unauthenticated},
+		{"read scope is enough to list", func() error {
+			_, err := c.List(as("reader"), &pb.ListTasksRequest{})
+			return err
+		}, codes.OK},
+		{"insufficient scope to create", func() error {
+			_, err := c.Create(as("reader"), &pb.CreateTaskRequest{Title: "x"})
+			return err
+		}, codes.PermissionDenied},
+		{"insufficient scope to rename tag", func() error {
+			_, err := c.RenameTag(as("reader"), &pb.RenameTagRequest{From: "a", To: "b"})
+			return err
+		}, codes.PermissionDenied},
+		{"insufficient scope is checked before the task", func() error {
+			_, err := c.Delete(as("reader"), &pb.DeleteTaskRequest{Id: "t_missing"})
+			return err
+		}, codes.PermissionDenied},
+		{"watch without Bearer prefix", func() error {
+			stream, err := c.Watch(withHeader("Basic YWxpY2U6"), &pb.WatchRequest{})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantCode(t, tt.call(), tt.want)
+		})
+	}
+}
+
+func TestTaskErrorCodes(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+	task, err := c.Create(as("alice"), &pb.CreateTaskRequest{Title: "задача"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	shared, err := c.tasks.Share(ctx, "alice", task.Id, "bob", service.PermissionViewer)
+	if err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+	stale := shared.Version + 1
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"unknown task", func() error {
+			_, err := c.Get(as("alice"), &pb.GetTaskRequest{Id: "t_missing"})
+			return err
+		}, codes.NotFound},
+		// Чужая задача неотличима от несуществующей
+		{"foreign task", func() error {
+			_, err := c.Get(as("reader"), &pb.GetTaskRequest{Id: task.Id})
+			return err
+		}, codes.NotFound},
+		{"viewer updates", func() error {
+			_, err := c.Update(as("bob"), &pb.UpdateTaskRequest{Id: task.Id, Done: proto.Bool(true)})
+			return err
+		}, codes.PermissionDenied},
+		{"viewer deletes", func() error {
+			_, err := c.Delete(as("bob"), &pb.DeleteTaskRequest{Id: task.Id})
+			return err
+		}, codes.PermissionDenied},
+		{"stale expected_version on update", func() error {
+			_, err := c.Update(as("alice"), &pb.UpdateTaskRequest{Id: task.Id, ExpectedVersion: stale, Done: proto.Bool(true)})
+			return err
+		}, codes.FailedPrecondition},
+		{"stale expected_version on delete", func() error {
+			_, err := c.Delete(as("alice"), &pb.DeleteTaskRequest{Id: task.Id, ExpectedVersion: stale})
+			return err
+		}, codes.FailedPrecondition},
+		{"empty title", func() error {
+			_, err := c.Create(as("alice"), &pb.CreateTaskRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"title cleared by update", func() error {
+			_, err := c.Update(as("alice"), &pb.UpdateTaskRequest{Id: task.Id, Title: proto.String("")})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid due_date", func() error {
+			_, err := c.Create(as("alice"), &pb.CreateTaskRequest{Title: "x", DueDate: "next friday"})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid tag", func() error {
+			_, err := c.Create(as("alice"), &pb.CreateTaskRequest{Title: "x", Tags: []string{"no spaces"}})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid due_before", func() error {
+			_, err := c.List(as("alice"), &pb.ListTasksRequest{DueBefore: "tomorrow"})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid cursor", func() error {
+			_, err := c.List(as("alice"), &pb.ListTasksRequest{Cursor: "garbage"})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown sort", func() error {
+			_, err := c.List(as("alice"), &pb.ListTasksRequest{Sort: "owner"})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid after_event_id", func() error {
+			stream, err := c.Watch(as("alice"), &pb.WatchRequest{AfterEventId: "abc"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantCode(t, tt.call(), tt.want)
+		})
+	}
+	// Ни один из отклонённых вызовов не изменил задачу
+	if got, err := c.Get(as("alice"), &pb.GetTaskRequest{Id: task.Id}); err != nil || got.Version != shared.Version || got.Done {
+		t.Fatalf("task after rejected calls = %v, %v; want version %d", got, err, shared.Version)
+	}
+}
+
+func TestUpdatePartialFields(t *testing.T) {
+	c := newTestClient(t)
+	task, err := c.Create(as("alice"), &pb.CreateTaskRequest{
+		Title:       "отчёт",
+		Description: "квартальный",
+		DueDate:     "2026-03-01",
+		Tags:        []string{"work"},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		req   *pb.UpdateTaskRequest
+		check func(t *testing.T, got *pb.Task)
+	}{
+		{
+			// Незаданные поля не меняются
+			name: "only done",
+			req:  &pb.UpdateTaskRequest{Done: proto.Bool(true)},
+			check: func(t *testing.T, got *pb.Task) {
+				if !got.Done || got.Title != "отчёт" || got.Description != "квартальный" || got.DueDate != "2026-03-01" || !slices.Equal(got.Tags, []string{"work"}) {
+					t.Fatalf("task = %v", got)
+				}
+			},
+		},
+		{
+			name: "title with expected_version",
+			req:  &pb.UpdateTaskRequest{ExpectedVersion: 2, Title: proto.String("итоговый отчёт")},
+			check: func(t *testing.T, got *pb.Task) {
+				if got.Title != "итоговый отчёт" || !got.Done || got.Version != 3 {
+					t.Fatalf("task = %v", got)
+				}
+			},
+		},
+		{
+			// Пустые значения, заданные явно, очищают поля
+			name: "clear description, due_date and tags",
+			req:  &pb.UpdateTaskRequest{Description: proto.String(""), DueDate: proto.String(""), Tags: &pb.TagList{}},
+			check: func(t *testing.T, got *pb.Task) {
+				if got.Description != "" || got.DueDate != "" || len(got.Tags) != 0 || got.Title != "итоговый отчёт" {
+					t.Fatalf("task = %v", got)
+				}
+			},
+		},
+		{
+			name: "tags are normalized",
+			req:  &pb.UpdateTaskRequest{Tags: &pb.TagList{Tags: []string{"Home", "home", " urgent "}}},
+			check: func(t *testing.T, got *pb.Task) {
+				if !slices.Equal(got.Tags, []string{"home", "urgent"}) {
+					t.Fatalf("tags = %v", got.Tags)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Id = task.Id
+			got, err := c.Update(as("alice"), tt.req)
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			tt.check(t, got)
+			if stored, err := c.Get(as("alice"), &pb.GetTaskRequest{Id: task.Id}); err != nil || !proto.Equal(stored, got) {
+				t.Fatalf("stored task = %v, %v; want %v", stored, err, got)
+			}
+		})
+	}
+}
+
+func TestDeleteWithExpectedVersion(t *testing.T) {
+	c := newTestClient(t)
+	task, err := c.Create(as("alice"), &pb.CreateTaskRequest{Title: "задача"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_, err = c.Delete(as("alice"), &pb.DeleteTaskRequest{Id: task.Id, ExpectedVersion: task.Version + 1})
+	wantCode(t, err, codes.FailedPrecondition)
+	if _, err := c.Delete(as("alice"), &pb.DeleteTaskRequest{Id: task.Id, ExpectedVersion: task.Version}); err != nil {
+		t.Fatalf("Delete with current version: %v", err)
+	}
+	_, err = c.Get(as("alice"), &pb.GetTaskRequest{Id: task.Id})
+	wantCode(t, err, codes.NotFound)
+}
+
+// drain публикует события outbox подписчикам, как это делает RunOutbox
+func (c *testClient) drain(t *testing.T) {
+	t.Helper()
+	if _, err := c.tasks.DrainOutbox(context.Background()); err != nil {
+		t.Fatalf("DrainOutbox: %v", err)
+	}
+}
+
+// recvEvent получает следующее событие потока Watch
+func recvEvent(t *testing.T, stream pb.TaskService_WatchClient) *pb.TaskEvent {
+	t.Helper()
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	return ev
+}
+
+func TestWatchResume(t *testing.T) {
+	// История из трёх событий, чтобы её было легко исчерпать
+	c := newTestClientWith(t, service.UUIDv7IDs, 3)
+	create := func(token, title string) *pb.Task {
+		t.Helper()
+		task, err := c.Create(as(token), &pb.CreateTaskRequest{Title: title})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		c.drain(t)
+		return task
+	}
+	watch := func(ctx context.Context, after string) pb.TaskService_WatchClient {
+		t.Helper()
+		stream, err := c.Watch(ctx, &pb.WatchRequest{AfterEventId: after})
+		if err != nil {
+			t.Fatalf("Watch: %v", err)
+		}
+		return stream
+	}
+
+	// id событий начинаются с текущего времени, поэтому узнаём их у подписки на шину
+	sub, _, _ := c.tasks.Watch("alice", 0)
+	first, second := create("alice", "первая"), create("alice", "вторая")
+	ev1, ev2 := toProtoEvent(<-sub.Events()), toProtoEvent(<-sub.Events())
+	sub.Close()
+	if ev1.Task.Id != first.Id || ev2.Task.Id != second.Id {
+		t.Fatalf("bus events = %v, %v", ev1, ev2)
+	}
+
+	ctx, cancel := context.WithTimeout(as("alice"), 5*time.Second)
+	defer cancel()
+
+	// Продолжение после ev1: сначала пропущенное событие, затем новые
+	resumed := watch(ctx, ev1.Id)
+	if ev := recvEvent(t, resumed); ev.Id != ev2.Id || ev.Type != pb.TaskEvent_CREATED {
+		t.Fatalf("missed event = %v, want %v", ev, ev2)
+	}
+	if _, err := c.Update(as("alice"), &pb.UpdateTaskRequest{Id: first.Id, Done: proto.Bool(true)}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	c.drain(t)
+	ev3 := recvEvent(t, resumed)
+	if ev3.Type != pb.TaskEvent_UPDATED || ev3.Task.Id != first.Id || !ev3.Task.Done {
+		t.Fatalf("live event after resume = %v, want update of %s", ev3, first.Id)
+	}
+
+	// Событие чужой задачи занимает место в истории, но в поток alice не попадает
+	create("bob", "чужая")
+	third := create("alice", "третья")
+	ev5 := recvEvent(t, resumed)
+	if ev5.Task.Id != third.Id {
+		t.Fatalf("event after foreign one = %v, want creation of %s", ev5, third.Id)
+	}
+	cancel()
+
+	id5, err := strconv.ParseUint(ev5.Id, 10, 64)
+	if err != nil {
+		t.Fatalf("event id %q: %v", ev5.Id, err)
+	}
+	tests := []struct {
+		name  string
+		after string
+		want  codes.Code
+	}{
+		// В истории остались три последних события: после ev2 продолжить можно, после ev1 — уже нет
+		{"history is kept", ev2.Id, codes.OK},
+		{"history is gone", ev1.Id, codes.OutOfRange},
+		// id из будущего — признак потока от другого экземпляра сервиса
+		{"unknown future id", strconv.FormatUint(id5+10, 10), codes.OutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(as("alice"), 5*time.Second)
+			defer cancel()
+			ev, err := watch(ctx, tt.after).Recv()
+			wantCode(t, err, tt.want)
+			if tt.want == codes.OK && ev.Id != ev3.Id {
+				t.Fatalf("first resumed event = %v, want %v", ev, ev3)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	c := newTestClient(t)
+	for _, tags := range [][]string{{"work", "urgent"}, {"work"}, {"home"}} {
+		if _, err := c.Create(as("alice"), &pb.CreateTaskRequest{Title: "задача", Tags: tags}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if _, err := c.Create(as("bob"), &pb.CreateTaskRequest{Title: "чужая", Tags: []string{"work"}}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	counts := func() map[string]int32 {
+		t.Helper()
+		resp, err := c.ListTags(as("alice"), &pb.ListTagsRequest{})
+		if err != nil {
+			t.Fatalf("ListTags: %v", err)
+		}
+		got := make(map[string]int32, len(resp.Tags))
+		for _, tag := range resp.Tags {
+			got[tag.Name] = tag.Count
+		}
+		return got
+	}
+	if got := counts(); got["work"] != 2 || got["urgent"] != 1 || got["home"] != 1 || len(got) != 3 {
+		t.Fatalf("tags = %v", got)
+	}
+
+	renamed, err := c.RenameTag(as("alice"), &pb.RenameTagRequest{From: "work", To: " Job "})
+	if err != nil || renamed.Name != "job" || renamed.Updated != 2 {
+		t.Fatalf("RenameTag = %v, %v; want job with 2 updated", renamed, err)
+	}
+	_, err = c.RenameTag(as("alice"), &pb.RenameTagRequest{From: "job", To: "home"})
+	wantCode(t, err, codes.AlreadyExists)
+	_, err = c.RenameTag(as("alice"), &pb.RenameTagRequest{From: "missing", To: "other"})
+	wantCode(t, err, codes.NotFound)
+	_, err = c.RenameTag(as("alice"), &pb.RenameTagRequest{From: "job", To: "no spaces"})
+	wantCode(t, err, codes.InvalidArgument)
+
+	merged, err := c.MergeTags(as("alice"), &pb.MergeTagsRequest{Sources: []string{"urgent", "home"}, Target: "job"})
+	if err != nil || merged.Name != "job" || merged.Updated != 2 {
+		t.Fatalf("MergeTags = %v, %v; want job with 2 updated", merged, err)
+	}
+	if got := counts(); got["job"] != 3 || len(got) != 1 {
+		t.Fatalf("tags after merge = %v", got)
+	}
+	// Теги bob не затронуты
+	resp, err := c.ListTags(as("bob"), &pb.ListTagsRequest{})
+	if err != nil || len(resp.Tags) != 1 || resp.Tags[0].Name != "work" || resp.Tags[0].Count != 1 {
+		t.Fatalf("bob's tags = %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	pb "github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/proto/tasks"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+func (s *Server) ListTags(ctx context.Context, req *pb.ListTagsRequest) (*pb.ListTagsResponse, error) {
+	ctx, logEntry := s.start(ctx, "ListTags")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksRead)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.taskService.Tags(ctx, identity.Subject)
+	if err != nil {
+		return nil, taskError(logEntry, err)
+	}
+	resp := &pb.ListTagsResponse{Tags: make([]*pb.TagCount, 0, len(tags))}
+	for _, t := range tags {
+		resp.Tags = append(resp.Tags, &pb.TagCount{Name: t.Name, Count: int32(t.Count)})
+	}
+	logEntry.WithField("count", len(resp.Tags)).Debug("tags listed")
+	return resp, nil
+}
+
+func (s *Server) RenameTag(ctx context.Context, req *pb.RenameTagRequest) (*pb.RetagResponse, error) {
+	ctx, logEntry := s.start(ctx, "RenameTag")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksWrite)
+	if err != nil {
+		return nil, err
+	}
+	logEntry = logEntry.WithField("tag", req.From)
+
+	updated, err := s.taskService.RenameTag(ctx, identity.Subject, req.From, req.To)
+	if err != nil {
+		return nil, taskError(logEntry, err)
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"name":    req.To,
+		"updated": updated,
+	}).Info("tag renamed successfully")
+	name, _ := service.NormalizeTag(req.To)
+	return &pb.RetagResponse{Name: name, Updated: int32(updated)}, nil
+}
+
+func (s *Server) MergeTags(ctx context.Context, req *pb.MergeTagsRequest) (*pb.RetagResponse, error) {
+	ctx, logEntry := s.start(ctx, "MergeTags")
+	ctx, identity, err := s.authenticate(ctx, logEntry, authclient.ScopeTasksWrite)
+	if err != nil {
+		return nil, err
+	}
+	logEntry = logEntry.WithField("tag", req.Target)
+
+	updated, err := s.taskService.MergeTags(ctx, identity.Subject, req.Sources, req.Target)
+	if err != nil {
+		return nil, taskError(logEntry, err)
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"sources": req.Sources,
+		"updated": updated,
+	}).Info("tags merged successfully")
+	name, _ := service.NormalizeTag(req.Target)
+	return &pb.RetagResponse{Name: name, Updated: int32(updated)}, nil
+}
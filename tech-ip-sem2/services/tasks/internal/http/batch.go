@@ -0,0 +1,219 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// Операции пакета в API; complete — сокращение для update с done=true
+const (
+	batchOpCreate   = "create"
+	batchOpUpdate   = "update"
+	batchOpComplete = "complete"
+	batchOpDelete   = "delete"
+)
+
+type batchRequest struct {
+	// Atomic — выполнить все операции или ни одной
+	Atomic     bool             `json:"atomic"`
+	Operations []batchOperation `json:"operations"`
+}
+
+type batchOperation struct {
+	Op      string             `json:"op"`
+	ID      string             `json:"id"`
+	IfMatch string             `json:"if_match"`
+	Task    *createTaskRequest `json:"task"`
+	// Patch — JSON Merge Patch для update
+	Patch json.RawMessage `json:"patch"`
+}
+
+type batchItemResponse struct {
+	Index  int           `json:"index"`
+	Status int           `json:"status"`
+	Task   *taskResponse `json:"task,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchItemResponse `json:"results"`
+}
+
+// toServiceOp проверяет операцию и переводит её в service.BatchOp
+func (o batchOperation) toServiceOp() (service.BatchOp, error) {
+	op := service.BatchOp{ID: o.ID, IfMatch: parseIfMatchValue(o.IfMatch)}
+	if o.Op != batchOpCreate && o.ID == "" {
+		return op, errors.New("id is required")
+	}
+	switch o.Op {
+	case batchOpCreate:
+		if o.Task == nil || o.Task.Title == "" {
+			return op, service.ErrTitleRequired
+		}
+		dueDate, err := parseDueDate(o.Task.DueDate)
+		if err != nil {
+			return op, err
+		}
+		op.Kind = service.BatchCreate
+		op.Task = service.Task{Title: o.Task.Title, Description: o.Task.Description, DueDate: dueDate, Tags: o.Task.Tags}
+	case batchOpUpdate:
+		if len(o.Patch) == 0 {
+			return op, errors.New("patch is required")
+		}
+		change, err := patchTask(contentTypeMergePatch, o.Patch)
+		if err != nil {
+			return op, err
+		}
+		op.Kind = service.BatchUpdate
+		op.Change = change
+	case batchOpComplete:
+		op.Kind = service.BatchUpdate
+		op.Change = func(t service.Task) (service.Task, error) {
+			t.Done = true
+			return t, nil
+		}
+	case batchOpDelete:
+		op.Kind = service.BatchDelete
+	default:
+		return op, fmt.Errorf("unknown op %q", o.Op)
+	}
+	return op, nil
+}
+
+// batchItemStatus переводит ошибку операции пакета в HTTP-статус и сообщение
+func batchItemStatus(err error) (int, string) {
+	var pe *patchError
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		return http.StatusNotFound, "task not found"
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, "forbidden"
+	case errors.Is(err, service.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, "precondition failed"
+	case errors.Is(err, service.ErrVersionConflict):
+		return http.StatusConflict, "task was modified concurrently"
+	case errors.Is(err, service.ErrTaskExists):
+		return http.StatusConflict, "task already exists"
+	case errors.Is(err, service.ErrShareNotFound):
+		return http.StatusNotFound, "share not found"
+	case errors.Is(err, service.ErrTagNotFound):
+		return http.StatusNotFound, "tag not found"
+	case errors.Is(err, service.ErrTagExists):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, service.ErrTitleRequired):
+		return http.StatusBadRequest, "title is required"
+	case errors.Is(err, service.ErrInvalidShare):
+		return http.StatusBadRequest, "invalid share"
+	case errors.Is(err, service.ErrInvalidTag),
+		errors.Is(err, service.ErrInvalidDueDate),
+		errors.Is(err, service.ErrInvalidQuery),
+		errors.Is(err, service.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidBatch):
+		return http.StatusBadRequest, err.Error()
+	case errors.As(err, &pe):
+		return http.StatusBadRequest, pe.Error()
+	case errors.Is(err, errPatchConflict):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, service.ErrBatchAborted):
+		return http.StatusFailedDependency, "batch aborted"
+	default:
+		return http.StatusInternalServerError, "internal error"
+	}
+}
+
+// BatchTasks обрабатывает POST /v1/tasks:batch. Операции выполняются по порядку,
+// для каждой возвращается свой статус. Если хоть одна операция атомарного пакета
+// не прошла, ответ получает её статус, а остальные операции — 424.
+func (h *TaskHandler) BatchTasks(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "BatchTasks",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logEntry.WithError(err).Warn("invalid request body")
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > service.MaxBatchSize {
+		logEntry.WithField("operations", len(req.Operations)).Warn("invalid batch size")
+		writeJSONError(w, fmt.Sprintf("batch must contain from 1 to %d operations", service.MaxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	ops := make([]service.BatchOp, len(req.Operations))
+	for i, o := range req.Operations {
+		op, err := o.toServiceOp()
+		if err != nil {
+			logEntry.WithError(err).WithField("index", i).Warn("invalid batch operation")
+			writeJSONError(w, fmt.Sprintf("operations[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		ops[i] = op
+	}
+
+	results, err := h.taskService.Batch(r.Context(), identity.Subject, ops, req.Atomic)
+	if err != nil {
+		writeTaskError(w, logEntry, err)
+		return
+	}
+
+	resp := batchResponse{Results: make([]batchItemResponse, len(results))}
+	status := http.StatusOK
+	failed := 0
+	for i, res := range results {
+		item := batchItemResponse{Index: i}
+		switch {
+		case res.Err != nil:
+			item.Status, item.Error = batchItemStatus(res.Err)
+			if item.Status == http.StatusInternalServerError {
+				logEntry.WithError(res.Err).WithField("index", i).Error("batch operation failed")
+			}
+			if req.Atomic && !errors.Is(res.Err, service.ErrBatchAborted) {
+				status = item.Status
+			}
+			failed++
+		case ops[i].Kind == service.BatchDelete:
+			item.Status = http.StatusNoContent
+		case ops[i].Kind == service.BatchCreate:
+			item.Status = http.StatusCreated
+		default:
+			item.Status = http.StatusOK
+		}
+		if res.Err == nil && ops[i].Kind != service.BatchDelete {
+			task := toTaskResponse(res.Task, loc)
+			item.Task = &task
+		}
+		resp.Results[i] = item
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"operations": len(ops),
+		"failed":     failed,
+		"atomic":     req.Atomic,
+	}).Info("batch processed")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+func TestBatchTasks(t *testing.T) {
+	// {first} и {second} заменяются id задач alice, созданных перед пакетом
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantItems  []int
+		// wantTitles — заголовки задач alice после пакета
+		wantTitles []string
+		// wantDone — выполнена ли задача {first} после пакета
+		wantDone bool
+	}{
+		{
+			name: "atomic applies all",
+			body: `{"atomic":true,"operations":[
+				{"op":"create","task":{"title":"третья"}},
+				{"op":"complete","id":"{first}","if_match":"\"1\""},
+				{"op":"delete","id":"{second}"}]}`,
+			wantStatus: http.StatusOK,
+			wantItems:  []int{http.StatusCreated, http.StatusOK, http.StatusNoContent},
+			wantTitles: []string{"первая", "третья"},
+			wantDone:   true,
+		},
+		{
+			name: "atomic rolls back on missing task",
+			body: `{"atomic":true,"operations":[
+				{"op":"create","task":{"title":"третья"}},
+				{"op":"complete","id":"{first}"},
+				{"op":"delete","id":"missing"},
+				{"op":"delete","id":"{second}"}]}`,
+			wantStatus: http.StatusNotFound,
+			wantItems:  []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound, http.StatusFailedDependency},
+			wantTitles: []string{"вторая", "первая"},
+		},
+		{
+			name: "atomic rolls back on stale if-match",
+			body: `{"atomic":true,"operations":[
+				{"op":"complete","id":"{first}"},
+				{"op":"update","id":"{second}","if_match":"\"7\"","patch":{"title":"новая"}}]}`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantItems:  []int{http.StatusFailedDependency, http.StatusPreconditionFailed},
+			wantTitles: []string{"вторая", "первая"},
+		},
+		{
+			// Операция над задачей, изменённой раньше в том же пакете, видит её новую версию
+			name: "atomic sees own changes",
+			body: `{"atomic":true,"operations":[
+				{"op":"update","id":"{first}","if_match":"\"1\"","patch":{"title":"первая 2"}},
+				{"op":"complete","id":"{first}","if_match":"\"2\""}]}`,
+			wantStatus: http.StatusOK,
+			wantItems:  []int{http.StatusOK, http.StatusOK},
+			wantTitles: []string{"вторая", "первая 2"},
+			wantDone:   true,
+		},
+		{
+			name: "best effort reports each item",
+			body: `{"operations":[
+				{"op":"create","task":{"title":"третья"}},
+				{"op":"delete","id":"missing"},
+				{"op":"complete","id":"{first}"},
+				{"op":"update","id":"{second}","if_match":"\"7\"","patch":{"title":"новая"}},
+				{"op":"update","id":"{second}","patch":{"title":null}},
+				{"op":"create","task":{"title":"четвёртая"}}]}`,
+			wantStatus: http.StatusOK,
+			wantItems: []int{
+				http.StatusCreated, http.StatusNotFound, http.StatusOK,
+				http.StatusPreconditionFailed, http.StatusBadRequest, http.StatusCreated,
+			},
+			wantTitles: []string{"вторая", "первая", "третья", "четвёртая"},
+			wantDone:   true,
+		},
+		{
+			// Ошибки проверки данных, найденные сервисом, — ошибки клиента, а не 500
+			name: "atomic rolls back on invalid tag",
+			body: `{"atomic":true,"operations":[
+				{"op":"complete","id":"{first}"},
+				{"op":"create","task":{"title":"третья","tags":["bad tag"]}}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantItems:  []int{http.StatusFailedDependency, http.StatusBadRequest},
+			wantTitles: []string{"вторая", "первая"},
+		},
+		{
+			name: "best effort reports invalid tags",
+			body: `{"operations":[
+				{"op":"create","task":{"title":"третья","tags":["-work"]}},
+				{"op":"update","id":"{second}","patch":{"tags":[""]}},
+				{"op":"complete","id":"{first}"}]}`,
+			wantStatus: http.StatusOK,
+			wantItems:  []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusOK},
+			wantTitles: []string{"вторая", "первая"},
+			wantDone:   true,
+		},
+		{
+			name: "best effort does not touch foreign task",
+			body: `{"operations":[
+				{"op":"delete","id":"{foreign}"},
+				{"op":"complete","id":"{first}"}]}`,
+			wantStatus: http.StatusOK,
+			wantItems:  []int{http.StatusNotFound, http.StatusOK},
+			wantTitles: []string{"вторая", "первая"},
+			wantDone:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			first := api.createTask("alice", `{"title":"первая"}`)
+			second := api.createTask("alice", `{"title":"вторая"}`)
+			foreign := api.createTask("bob", `{"title":"чужая"}`)
+			body := strings.NewReplacer("{first}", first.ID, "{second}", second.ID, "{foreign}", foreign.ID).Replace(tt.body)
+
+			rec := api.do(http.MethodPost, "/v1/tasks:batch", "alice", body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			resp := decode[batchResponse](t, rec)
+			if got := itemStatuses(resp); !slices.Equal(got, tt.wantItems) {
+				t.Fatalf("item statuses = %v, want %v", got, tt.wantItems)
+			}
+			for _, item := range resp.Results {
+				switch {
+				case item.Status >= http.StatusBadRequest && item.Error == "":
+					t.Errorf("item %d: status %d without error", item.Index, item.Status)
+				case item.Status < http.StatusBadRequest && item.Status != http.StatusNoContent && item.Task == nil:
+					t.Errorf("item %d: status %d without task", item.Index, item.Status)
+				}
+			}
+
+			list := decode[listTasksResponse](t, api.do(http.MethodGet, "/v1/tasks", "alice", ""))
+			var titles []string
+			for _, task := range list.Items {
+				titles = append(titles, task.Title)
+			}
+			slices.Sort(titles)
+			want := slices.Clone(tt.wantTitles)
+			slices.Sort(want)
+			if !slices.Equal(titles, want) {
+				t.Fatalf("tasks after batch = %v, want %v", titles, want)
+			}
+
+			got := api.do(http.MethodGet, "/v1/tasks/"+first.ID, "alice", "")
+			if got.Code == http.StatusOK && decode[taskResponse](t, got).Done != tt.wantDone {
+				t.Fatalf("first task done = %v, want %v", !tt.wantDone, tt.wantDone)
+			}
+			if rec := api.do(http.MethodGet, "/v1/tasks/"+foreign.ID, "bob", ""); rec.Code != http.StatusOK {
+				t.Fatalf("foreign task after batch: status %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestBatchTasksRejectsInvalidBatch(t *testing.T) {
+	many := make([]string, service.MaxBatchSize+1)
+	for i := range many {
+		many[i] = fmt.Sprintf(`{"op":"create","task":{"title":"t%d"}}`, i)
+	}
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", `{"operations":[]}`},
+		{"too many", `{"operations":[` + strings.Join(many, ",") + `]}`},
+		{"unknown op", `{"operations":[{"op":"archive","id":"x"}]}`},
+		{"missing id", `{"operations":[{"op":"delete"}]}`},
+		{"missing patch", `{"operations":[{"op":"update","id":"x"}]}`},
+		{"not json", `operations`},
+	}
+
+	api := newTestAPI(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := api.do(http.MethodPost, "/v1/tasks:batch", "alice", tt.body); rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400, body %s", rec.Code, rec.Body)
+			}
+		})
+	}
+	// Отклонённый пакет не выполняет ни одной операции
+	if list := decode[listTasksResponse](t, api.do(http.MethodGet, "/v1/tasks", "alice", "")); len(list.Items) != 0 {
+		t.Fatalf("tasks after rejected batches = %d, want 0", len(list.Items))
+	}
+}
+
+func itemStatuses(resp batchResponse) []int {
+	statuses := make([]int, len(resp.Results))
+	for i, item := range resp.Results {
+		statuses[i] = item.Status
+	}
+	return statuses
+}
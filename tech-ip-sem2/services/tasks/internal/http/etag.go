@@ -0,0 +1,59 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// etag — сильный ETag задачи, построенный по её версии
+func etag(t service.Task) string {
+	return `"` + strconv.FormatInt(t.Version, 10) + `"`
+}
+
+func setETag(w http.ResponseWriter, t service.Task) {
+	w.Header().Set("ETag", etag(t))
+}
+
+func parseIfMatch(r *http.Request) service.IfMatch {
+	return parseIfMatchValue(r.Header.Get("If-Match"))
+}
+
+// parseIfMatchValue переводит значение If-Match в набор допустимых версий.
+// Без заголовка и для "*" условие не накладывается. Слабые и чужие ETag
+// ни с одной версией не совпадают (RFC 9110, сильное сравнение).
+func parseIfMatchValue(header string) service.IfMatch {
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return nil
+	}
+	versions := service.IfMatch{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// noneMatch сообщает, совпадает ли текущий ETag задачи с If-None-Match
+// (слабое сравнение): в этом случае GET отвечает 304
+func noneMatch(r *http.Request, t service.Task) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := etag(t)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
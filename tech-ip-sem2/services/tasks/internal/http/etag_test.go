@@ -0,0 +1,137 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+func TestParseIfMatchValue(t *testing.T) {
+	tests := []struct {
+		header string
+		want   service.IfMatch
+	}{
+		{"", nil},
+		{"*", nil},
+		{` * `, nil},
+		{`"3"`, service.IfMatch{3}},
+		{`"3", "5"`, service.IfMatch{3, 5}},
+		// Слабые, чужие и нечисловые ETag не совпадают ни с одной версией
+		{`W/"3"`, service.IfMatch{}},
+		{`3`, service.IfMatch{}},
+		{`"abc"`, service.IfMatch{}},
+		{`W/"3", "4"`, service.IfMatch{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got := parseIfMatchValue(tt.header)
+			if (got == nil) != (tt.want == nil) || !slices.Equal(got, tt.want) {
+				t.Fatalf("parseIfMatchValue(%q) = %#v, want %#v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfMatchPreconditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		ifMatch string
+		want    int
+	}{
+		{"patch with current etag", http.MethodPatch, `{"done":true}`, `"1"`, http.StatusOK},
+		{"patch with one of etags", http.MethodPatch, `{"done":true}`, `"7", "1"`, http.StatusOK},
+		{"patch with any etag", http.MethodPatch, `{"done":true}`, `*`, http.StatusOK},
+		{"patch without if-match", http.MethodPatch, `{"done":true}`, "", http.StatusOK},
+		{"patch with stale etag", http.MethodPatch, `{"done":true}`, `"2"`, http.StatusPreconditionFailed},
+		{"patch with weak etag", http.MethodPatch, `{"done":true}`, `W/"1"`, http.StatusPreconditionFailed},
+		{"put with stale etag", http.MethodPut, `{"title":"заменена"}`, `"0"`, http.StatusPreconditionFailed},
+		{"put with current etag", http.MethodPut, `{"title":"заменена"}`, `"1"`, http.StatusOK},
+		{"delete with stale etag", http.MethodDelete, "", `"5"`, http.StatusPreconditionFailed},
+		{"delete with current etag", http.MethodDelete, "", `"1"`, http.StatusNoContent},
+	}
+
+	api := newTestAPI(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := api.createTask("alice", `{"title":"условная"}`)
+			var header []string
+			if tt.ifMatch != "" {
+				header = []string{"If-Match", tt.ifMatch}
+			}
+			rec := api.do(tt.method, "/v1/tasks/"+task.ID, "alice", tt.body, header...)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.want, rec.Body)
+			}
+
+			get := api.do(http.MethodGet, "/v1/tasks/"+task.ID, "alice", "")
+			switch {
+			case tt.want == http.StatusPreconditionFailed:
+				// Несовпавшее условие ничего не меняет
+				if get.Code != http.StatusOK || get.Header().Get("ETag") != `"1"` {
+					t.Fatalf("after 412: status %d, ETag %s", get.Code, get.Header().Get("ETag"))
+				}
+			case tt.method == http.MethodDelete:
+				if get.Code != http.StatusNotFound {
+					t.Fatalf("after delete: status %d", get.Code)
+				}
+			default:
+				if etag := rec.Header().Get("ETag"); etag != `"2"` || get.Header().Get("ETag") != etag {
+					t.Fatalf("ETag after update = %s, stored %s, want \"2\"", etag, get.Header().Get("ETag"))
+				}
+			}
+		})
+	}
+}
+
+func TestETagChangesOnUpdate(t *testing.T) {
+	api := newTestAPI(t)
+	rec := api.do(http.MethodPost, "/v1/tasks", "alice", `{"title":"версии"}`)
+	created := decode[taskResponse](t, rec)
+	first := rec.Header().Get("ETag")
+	if first == "" {
+		t.Fatal("create response has no ETag")
+	}
+
+	etags := []string{first}
+	for _, body := range []string{`{"done":true}`, `{"title":"версии 2"}`} {
+		rec := api.do(http.MethodPatch, "/v1/tasks/"+created.ID, "alice", body, "If-Match", etags[len(etags)-1])
+		if rec.Code != http.StatusOK {
+			t.Fatalf("PATCH: status %d, body %s", rec.Code, rec.Body)
+		}
+		etag := rec.Header().Get("ETag")
+		if slices.Contains(etags, etag) {
+			t.Fatalf("ETag %s repeats after update, previous %v", etag, etags)
+		}
+		etags = append(etags, etag)
+	}
+
+	// Первый клиент, не видевший изменений, получает 412 вместо потери чужого обновления
+	if rec := api.do(http.MethodPatch, "/v1/tasks/"+created.ID, "alice", `{"done":false}`, "If-Match", first); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PATCH with first ETag: status %d, want 412", rec.Code)
+	}
+
+	current := etags[len(etags)-1]
+	tests := []struct {
+		ifNoneMatch string
+		want        int
+	}{
+		{current, http.StatusNotModified},
+		{"W/" + current, http.StatusNotModified},
+		{`"0", ` + current, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{first, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := api.do(http.MethodGet, "/v1/tasks/"+created.ID, "alice", "", "If-None-Match", tt.ifNoneMatch)
+		if rec.Code != tt.want {
+			t.Errorf("GET with If-None-Match %s: status %d, want %d", tt.ifNoneMatch, rec.Code, tt.want)
+		}
+		if rec.Header().Get("ETag") != current {
+			t.Errorf("GET with If-None-Match %s: ETag %s, want %s", tt.ifNoneMatch, rec.Header().Get("ETag"), current)
+		}
+	}
+}
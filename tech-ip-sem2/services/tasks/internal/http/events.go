@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// sseHeartbeat — период комментариев-пингов, чтобы прокси не закрывали простаивающий поток
+const sseHeartbeat = 15 * time.Second
+
+// WatchTasks обрабатывает GET /v1/tasks/events: изменения видимых задач в формате
+// Server-Sent Events. С заголовком Last-Event-ID поток продолжается после этого события;
+// если пропущенные события уже не сохранились, первым приходит событие reset —
+// список задач нужно перечитать.
+func (h *TaskHandler) WatchTasks(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "WatchTasks",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var after uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+			logEntry.WithField("last_event_id", v).Warn("invalid Last-Event-ID")
+			writeJSONError(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	sub, missed, resumed := h.taskService.Watch(identity.Subject, after)
+	defer sub.Close()
+
+	logEntry = logEntry.WithField("subject", identity.Subject)
+	logEntry.WithFields(logrus.Fields{
+		"last_event_id": after,
+		"missed":        len(missed),
+		"resumed":       resumed,
+	}).Info("event stream opened")
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if !resumed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, ev := range missed {
+		if err := writeEvent(w, ev, loc); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		logEntry.WithError(err).Error("streaming is not supported")
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			logEntry.Debug("event stream closed by client")
+			return
+		case ev, ok := <-sub.Events():
+			if !ok {
+				// Клиент переподключится с Last-Event-ID и получит пропущенное
+				logEntry.Warn("event stream subscriber fell behind")
+				return
+			}
+			if err := writeEvent(w, ev, loc); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent пишет событие SSE: id, тип и задачу в том же виде, что и REST API
+func writeEvent(w io.Writer, ev service.Event, loc *time.Location) error {
+	data, err := json.Marshal(toTaskResponse(ev.Task, loc))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
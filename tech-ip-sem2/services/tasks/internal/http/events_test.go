@@ -0,0 +1,152 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent — событие потока Server-Sent Events
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// sseStream — открытый поток /v1/tasks/events
+type sseStream struct {
+	t      *testing.T
+	resp   *http.Response
+	reader *bufio.Reader
+}
+
+// openEvents подключается к потоку событий от имени token; header — пары имя, значение
+func openEvents(t *testing.T, ctx context.Context, srv *httptest.Server, token string, header ...string) *sseStream {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/tasks/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/tasks/events: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return &sseStream{t: t, resp: resp, reader: bufio.NewReader(resp.Body)}
+}
+
+// next читает следующее событие, пропуская комментарии
+func (s *sseStream) next() sseEvent {
+	s.t.Helper()
+	var ev sseEvent
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			s.t.Fatalf("read event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if ev != (sseEvent{}) {
+				return ev
+			}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			ev.id = value
+		case "event":
+			ev.event = value
+		case "data":
+			ev.data = value
+		}
+	}
+}
+
+// task разбирает задачу из data события
+func (ev sseEvent) task(t *testing.T) taskResponse {
+	t.Helper()
+	var task taskResponse
+	if err := json.Unmarshal([]byte(ev.data), &task); err != nil {
+		t.Fatalf("decode event data %q: %v", ev.data, err)
+	}
+	return task
+}
+
+func TestWatchTasksResume(t *testing.T) {
+	api := newTestAPI(t)
+	srv := httptest.NewServer(api.handler)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	drain := func() {
+		t.Helper()
+		if _, err := api.tasks.DrainOutbox(ctx); err != nil {
+			t.Fatalf("DrainOutbox: %v", err)
+		}
+	}
+
+	// Ответ приходит после подписки, поэтому события после него не теряются
+	live := openEvents(t, ctx, srv, "alice")
+	if ct := live.resp.Header.Get("Content-Type"); live.resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("stream: status %d, Content-Type %q", live.resp.StatusCode, ct)
+	}
+	first := api.createTask("alice", `{"title":"первая"}`)
+	second := api.createTask("alice", `{"title":"вторая","due_date":"2026-03-01T18:00:00Z"}`)
+	drain()
+	ev1, ev2 := live.next(), live.next()
+	if ev1.event != "created" || ev1.task(t).ID != first.ID || ev2.task(t).ID != second.ID {
+		t.Fatalf("live events = %+v, %+v", ev1, ev2)
+	}
+
+	// С Last-Event-ID поток начинается с пропущенных событий, затем идут новые
+	resumed := openEvents(t, ctx, srv, "alice", "Last-Event-ID", ev1.id, "X-Timezone", "Europe/Moscow")
+	missed := resumed.next()
+	if missed.id != ev2.id || missed.event != "created" {
+		t.Fatalf("first resumed event = %+v, want %+v", missed, ev2)
+	}
+	if due := missed.task(t).DueDate; due != "2026-03-01T21:00:00+03:00" {
+		t.Fatalf("due_date in X-Timezone = %q", due)
+	}
+	if rec := api.do(http.MethodPatch, "/v1/tasks/"+first.ID, "alice", `{"done":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("patch: status %d, body %s", rec.Code, rec.Body)
+	}
+	drain()
+	if ev := resumed.next(); ev.event != "updated" || ev.task(t).ID != first.ID || !ev.task(t).Done {
+		t.Fatalf("live event after resume = %+v", ev)
+	}
+
+	// Событий, которые уже не сохранились, первым приходит reset
+	reset := openEvents(t, ctx, srv, "alice", "Last-Event-ID", "1")
+	if ev := reset.next(); ev.event != "reset" || ev.id != "" {
+		t.Fatalf("first event after lost history = %+v, want reset", ev)
+	}
+	if rec := api.do(http.MethodDelete, "/v1/tasks/"+second.ID, "alice", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", rec.Code)
+	}
+	drain()
+	if ev := reset.next(); ev.event != "deleted" || ev.task(t).ID != second.ID {
+		t.Fatalf("event after reset = %+v", ev)
+	}
+}
+
+func TestWatchTasksRejectsInvalidHeaders(t *testing.T) {
+	api := newTestAPI(t)
+	for _, header := range [][]string{
+		{"Last-Event-ID", "abc"},
+		{"X-Timezone", "Mars/Olympus_Mons"},
+	} {
+		if rec := api.do(http.MethodGet, "/v1/tasks/events", "alice", "", header...); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %q: status %d, want %d", header[0], header[1], rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -2,102 +2,244 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/idempotency"
 	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
 )
 
 // TaskHandler содержит зависимости для обработки HTTP-запросов
 type TaskHandler struct {
 	taskService *service.TaskService
-	authClient  *authclient.Client
+	authClient  authclient.Verifier
+	idempotency *idempotency.Store
+	webhooks    *webhook.Service
 	logger      *logrus.Logger
 }
 
 // NewTaskHandler создаёт новый экземпляр обработчика
-func NewTaskHandler(ts *service.TaskService, ac *authclient.Client, logger *logrus.Logger) *TaskHandler {
+func NewTaskHandler(ts *service.TaskService, ac authclient.Verifier, idem *idempotency.Store, webhooks *webhook.Service, logger *logrus.Logger) *TaskHandler {
 	return &TaskHandler{
 		taskService: ts,
 		authClient:  ac,
+		idempotency: idem,
+		webhooks:    webhooks,
 		logger:      logger,
 	}
 }
 
-// verifyToken проверяет токен через gRPC вызов к Auth service
-func (h *TaskHandler) verifyToken(w http.ResponseWriter, r *http.Request) bool {
-	requestID := middleware.GetRequestID(r.Context())
-	logEntry := h.logger.WithFields(logrus.Fields{
-		"component":  "http_handler",
-		"request_id": requestID,
-	})
-
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		logEntry.Warn("missing authorization header")
-		http.Error(w, `{"error":"missing authorization header"}`, http.StatusUnauthorized)
-		return false
+// Require защищает маршрут: проверяет токен через gRPC вызов к Auth service и наличие
+// у его владельца разрешения scope, которое маршрут объявляет при регистрации.
+// Владелец токена передаётся обработчику в контексте запроса, а запрос с scope
+// tasks:admin помечается как административный.
+func (h *TaskHandler) Require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := middleware.GetRequestID(r.Context())
+		logEntry := h.logger.WithFields(logrus.Fields{
+			"component":  "http_handler",
+			"request_id": requestID,
+		})
+
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			logEntry.Warn("missing authorization header")
+			http.Error(w, `{"error":"missing authorization header"}`, http.StatusUnauthorized)
+			return
+		}
+
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			logEntry.WithField("auth_header", authHeader).Warn("invalid authorization header format")
+			http.Error(w, `{"error":"invalid authorization header format"}`, http.StatusUnauthorized)
+			return
+		}
+		token := parts[1]
+
+		valid, identity, err := h.authClient.VerifyToken(r.Context(), token)
+		if err != nil {
+			logEntry.WithError(err).Error("authentication service unavailable")
+			http.Error(w, `{"error":"authentication service unavailable"}`, http.StatusServiceUnavailable)
+			return
+		}
+		if !valid {
+			logEntry.WithField("token_present", token != "").Warn("invalid token")
+			http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if !h.checkScope(w, logEntry, identity, scope) {
+			return
+		}
+
+		logEntry.WithField("subject", identity.Subject).Debug("token verified successfully")
+		ctx := withIdentity(r.Context(), identity)
+		if identity.HasScope(authclient.ScopeTasksAdmin) {
+			ctx = service.WithAdmin(ctx)
+		}
+		next(w, r.WithContext(ctx))
 	}
+}
 
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		logEntry.WithField("auth_header", authHeader).Warn("invalid authorization header format")
-		http.Error(w, `{"error":"invalid authorization header format"}`, http.StatusUnauthorized)
-		return false
+// identity возвращает владельца токена, проверенного Require. Маршрут без Require —
+// ошибка регистрации, поэтому запрос отклоняется, а не выполняется анонимно.
+func (h *TaskHandler) identity(w http.ResponseWriter, r *http.Request) (authclient.Identity, bool) {
+	identity, ok := identityFromContext(r.Context())
+	if !ok {
+		h.logger.WithFields(logrus.Fields{
+			"component":  "http_handler",
+			"request_id": middleware.GetRequestID(r.Context()),
+			"path":       r.URL.Path,
+		}).Error("route is not protected by Require")
+		http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
 	}
-	token := parts[1]
+	return identity, ok
+}
 
-	valid, _, err := h.authClient.VerifyToken(r.Context(), token)
-	if err != nil {
-		logEntry.WithError(err).Error("authentication service unavailable")
-		http.Error(w, `{"error":"authentication service unavailable"}`, http.StatusServiceUnavailable)
-		return false
-	}
-	if !valid {
-		logEntry.WithField("token_present", token != "").Warn("invalid token")
-		http.Error(w, `{"error":"invalid token"}`, http.StatusUnauthorized)
-		return false
+func (h *TaskHandler) checkScope(w http.ResponseWriter, logEntry *logrus.Entry, identity authclient.Identity, scope string) bool {
+	if identity.HasScope(scope) {
+		return true
 	}
+	logEntry.WithFields(logrus.Fields{
+		"subject": identity.Subject,
+		"scope":   scope,
+	}).Warn("insufficient scope")
+	http.Error(w, `{"error":"insufficient scope"}`, http.StatusForbidden)
+	return false
+}
+
+// writeJSONError отвечает ошибкой в формате {"error": "..."} с экранированием текста
+func writeJSONError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
 
-	logEntry.Debug("token verified successfully")
-	return true
+// writeTaskError переводит ошибки TaskService в HTTP-ответы
+func writeTaskError(w http.ResponseWriter, logEntry *logrus.Entry, err error) {
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		logEntry.Warn("task not found")
+		http.Error(w, `{"error":"task not found"}`, http.StatusNotFound)
+	case errors.Is(err, service.ErrForbidden):
+		logEntry.Warn("access to task denied")
+		http.Error(w, `{"error":"forbidden"}`, http.StatusForbidden)
+	case errors.Is(err, service.ErrShareNotFound):
+		logEntry.Warn("share not found")
+		http.Error(w, `{"error":"share not found"}`, http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidShare):
+		logEntry.Warn("invalid share")
+		http.Error(w, `{"error":"invalid share"}`, http.StatusBadRequest)
+	case errors.Is(err, service.ErrPreconditionFailed):
+		logEntry.Warn("task version does not match If-Match")
+		http.Error(w, `{"error":"precondition failed"}`, http.StatusPreconditionFailed)
+	case errors.Is(err, service.ErrVersionConflict):
+		logEntry.Warn("concurrent task modification")
+		http.Error(w, `{"error":"task was modified concurrently"}`, http.StatusConflict)
+	case errors.Is(err, service.ErrTaskExists):
+		logEntry.WithError(err).Error("task id collision")
+		http.Error(w, `{"error":"task already exists"}`, http.StatusConflict)
+	case errors.Is(err, service.ErrTitleRequired):
+		logEntry.Warn("title is required")
+		http.Error(w, `{"error":"title is required"}`, http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidTag):
+		logEntry.WithError(err).Warn("invalid tag")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrTagNotFound):
+		logEntry.Warn("tag not found")
+		http.Error(w, `{"error":"tag not found"}`, http.StatusNotFound)
+	case errors.Is(err, service.ErrTagExists):
+		logEntry.WithError(err).Warn("tag already exists")
+		writeJSONError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidQuery), errors.Is(err, service.ErrInvalidCursor):
+		logEntry.WithError(err).Warn("invalid list query")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logEntry.WithError(err).Error("task operation failed")
+		http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+	}
 }
 
 // Структуры запросов
 type createTaskRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	DueDate     string `json:"due_date"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	DueDate     string   `json:"due_date"`
+	Tags        []string `json:"tags"`
 }
 
-type updateTaskRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	DueDate     string `json:"due_date"`
-	Done        bool   `json:"done"`
+type shareRequest struct {
+	Subject    string `json:"subject"`
+	Permission string `json:"permission"`
 }
 
 // Структура ответа с задачей
 type taskResponse struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	DueDate     string `json:"due_date,omitempty"`
-	Done        bool   `json:"done"`
+	ID          string                        `json:"id"`
+	Owner       string                        `json:"owner"`
+	Title       string                        `json:"title"`
+	Description string                        `json:"description"`
+	DueDate     string                        `json:"due_date,omitempty"`
+	Done        bool                          `json:"done"`
+	Shares      map[string]service.Permission `json:"shares,omitempty"`
+	Tags        []string                      `json:"tags,omitempty"`
+	Version     int64                         `json:"version"`
+	CreatedAt   string                        `json:"created_at"`
+	UpdatedAt   string                        `json:"updated_at"`
+}
+
+// listTasksResponse — страница списка задач
+type listTasksResponse struct {
+	Items      []taskResponse `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 }
 
-// toTaskResponse преобразует внутреннюю модель Task в response
-func toTaskResponse(t service.Task) taskResponse {
+// toTaskResponse преобразует внутреннюю модель Task в response;
+// метки времени выводятся в часовом поясе loc
+func toTaskResponse(t service.Task, loc *time.Location) taskResponse {
 	return taskResponse{
 		ID:          t.ID,
+		Owner:       t.Owner,
 		Title:       t.Title,
 		Description: t.Description,
-		DueDate:     t.DueDate,
+		DueDate:     t.DueDate.Format(loc),
 		Done:        t.Done,
+		Shares:      t.Shares,
+		Tags:        t.Tags,
+		Version:     t.Version,
+		CreatedAt:   t.CreatedAt.In(loc).Format(time.RFC3339),
+		UpdatedAt:   t.UpdatedAt.In(loc).Format(time.RFC3339),
+	}
+}
+
+// parseDueDate разбирает необязательный срок из тела запроса; пустая строка — срок не задан
+func parseDueDate(raw string) (service.DueDate, error) {
+	if raw == "" {
+		return service.DueDate{}, nil
+	}
+	return service.ParseDueDate(raw)
+}
+
+// requestLocation возвращает часовой пояс из заголовка X-Timezone (имя IANA,
+// например Europe/Moscow), в котором отображаются даты ответа; по умолчанию UTC
+func requestLocation(r *http.Request) (*time.Location, error) {
+	name := r.Header.Get("X-Timezone")
+	if name == "" {
+		return time.UTC, nil
 	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X-Timezone %q", name)
+	}
+	return loc, nil
 }
 
 // CreateTask обрабатывает POST /v1/tasks
@@ -109,7 +251,14 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		"request_id": requestID,
 	})
 
-	if !h.verifyToken(w, r) {
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -126,19 +275,33 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	dueDate, err := parseDueDate(req.DueDate)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid due date")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	task := service.Task{
+		Owner:       identity.Subject,
 		Title:       req.Title,
 		Description: req.Description,
-		DueDate:     req.DueDate,
+		DueDate:     dueDate,
 		Done:        false,
+		Tags:        req.Tags,
+	}
+	created, err := h.taskService.Create(r.Context(), task)
+	if err != nil {
+		writeTaskError(w, logEntry, err)
+		return
 	}
-	created := h.taskService.Create(task)
 
 	logEntry.WithField("task_id", created.ID).Info("task created successfully")
 
+	setETag(w, created)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(toTaskResponse(created))
+	json.NewEncoder(w).Encode(toTaskResponse(created, loc))
 }
 
 // ListTasks обрабатывает GET /v1/tasks
@@ -150,17 +313,38 @@ func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 		"request_id": requestID,
 	})
 
-	if !h.verifyToken(w, r) {
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, err := parseListQuery(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid list query")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	tasks := h.taskService.List()
-	resp := make([]taskResponse, len(tasks))
-	for i, t := range tasks {
-		resp[i] = toTaskResponse(t)
+	page, err := h.taskService.List(r.Context(), identity.Subject, query)
+	if err != nil {
+		writeTaskError(w, logEntry, err)
+		return
+	}
+	resp := listTasksResponse{
+		Items:      make([]taskResponse, len(page.Tasks)),
+		NextCursor: page.NextCursor,
+	}
+	for i, t := range page.Tasks {
+		resp.Items[i] = toTaskResponse(t, loc)
 	}
 
-	logEntry.WithField("count", len(tasks)).Debug("tasks listed")
+	logEntry.WithField("count", len(page.Tasks)).Debug("tasks listed")
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -175,25 +359,40 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		"request_id": requestID,
 	})
 
-	if !h.verifyToken(w, r) {
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	id := r.PathValue("id")
-	task, ok := h.taskService.Get(id)
-	if !ok {
-		logEntry.WithField("task_id", id).Warn("task not found")
-		http.Error(w, `{"error":"task not found"}`, http.StatusNotFound)
+	task, err := h.taskService.Get(r.Context(), identity.Subject, id)
+	if err != nil {
+		writeTaskError(w, logEntry.WithField("task_id", id), err)
+		return
+	}
+
+	setETag(w, task)
+	if noneMatch(r, task) {
+		logEntry.WithField("task_id", id).Debug("task not modified")
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	logEntry.WithField("task_id", id).Debug("task retrieved")
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(toTaskResponse(task))
+	json.NewEncoder(w).Encode(toTaskResponse(task, loc))
 }
 
-// UpdateTask обрабатывает PATCH /v1/tasks/{id}
+// UpdateTask обрабатывает PATCH /v1/tasks/{id}. Формат тела определяется по Content-Type:
+// application/merge-patch+json (или application/json) — JSON Merge Patch,
+// application/json-patch+json — JSON Patch
 func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	requestID := middleware.GetRequestID(r.Context())
 	logEntry := h.logger.WithFields(logrus.Fields{
@@ -202,35 +401,113 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		"request_id": requestID,
 	})
 
-	if !h.verifyToken(w, r) {
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	id := r.PathValue("id")
-	var req updateTaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	mediaType, err := patchMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		logEntry.WithField("content_type", r.Header.Get("Content-Type")).Warn("unsupported patch content type")
+		w.Header().Set("Accept-Patch", contentTypeMergePatch+", "+contentTypeJSONPatch)
+		writeJSONError(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
 		logEntry.WithError(err).Warn("invalid request body")
 		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
 		return
 	}
+	change, err := patchTask(mediaType, body)
+	if err != nil {
+		writeUpdateError(w, logEntry.WithField("task_id", id), err)
+		return
+	}
 
-	updatedTask := service.Task{
-		Title:       req.Title,
-		Description: req.Description,
-		DueDate:     req.DueDate,
-		Done:        req.Done,
+	task, err := h.taskService.Update(r.Context(), identity.Subject, id, parseIfMatch(r), change)
+	if err != nil {
+		writeUpdateError(w, logEntry.WithField("task_id", id), err)
+		return
 	}
-	task, ok := h.taskService.Update(id, updatedTask)
+
+	logEntry.WithFields(logrus.Fields{
+		"task_id":    id,
+		"patch_type": mediaType,
+	}).Info("task updated successfully")
+
+	setETag(w, task)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toTaskResponse(task, loc))
+}
+
+// ReplaceTask обрабатывает PUT /v1/tasks/{id}: задача целиком заменяется телом запроса,
+// отсутствующие поля получают значения по умолчанию
+func (h *TaskHandler) ReplaceTask(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "ReplaceTask",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
 	if !ok {
-		logEntry.WithField("task_id", id).Warn("task not found for update")
-		http.Error(w, `{"error":"task not found"}`, http.StatusNotFound)
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid request body")
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	doc, err := decodeTaskDocument(body)
+	if err != nil {
+		writeUpdateError(w, logEntry.WithField("task_id", id), err)
+		return
+	}
+
+	task, err := h.taskService.Update(r.Context(), identity.Subject, id, parseIfMatch(r), doc.apply)
+	if err != nil {
+		writeUpdateError(w, logEntry.WithField("task_id", id), err)
 		return
 	}
 
-	logEntry.WithField("task_id", id).Info("task updated successfully")
+	logEntry.WithField("task_id", id).Info("task replaced successfully")
 
+	setETag(w, task)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(toTaskResponse(task))
+	json.NewEncoder(w).Encode(toTaskResponse(task, loc))
+}
+
+// writeUpdateError дополняет writeTaskError ошибками разбора и применения патча
+func writeUpdateError(w http.ResponseWriter, logEntry *logrus.Entry, err error) {
+	var pe *patchError
+	switch {
+	case errors.As(err, &pe):
+		logEntry.WithError(err).Warn("invalid patch")
+		writeJSONError(w, pe.Error(), http.StatusBadRequest)
+	case errors.Is(err, errPatchConflict):
+		logEntry.Warn("patch test operation failed")
+		writeJSONError(w, err.Error(), http.StatusConflict)
+	default:
+		writeTaskError(w, logEntry, err)
+	}
 }
 
 // DeleteTask обрабатывает DELETE /v1/tasks/{id}
@@ -242,18 +519,90 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		"request_id": requestID,
 	})
 
-	if !h.verifyToken(w, r) {
+	identity, ok := h.identity(w, r)
+	if !ok {
 		return
 	}
 
 	id := r.PathValue("id")
-	ok := h.taskService.Delete(id)
-	if !ok {
-		logEntry.WithField("task_id", id).Warn("task not found for deletion")
-		http.Error(w, `{"error":"task not found"}`, http.StatusNotFound)
+	if err := h.taskService.Delete(r.Context(), identity.Subject, id, parseIfMatch(r)); err != nil {
+		writeTaskError(w, logEntry.WithField("task_id", id), err)
 		return
 	}
 
 	logEntry.WithField("task_id", id).Info("task deleted successfully")
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ShareTask обрабатывает POST /v1/tasks/{id}/shares
+func (h *TaskHandler) ShareTask(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "ShareTask",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	var req shareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logEntry.WithError(err).Warn("invalid request body")
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.taskService.Share(r.Context(), identity.Subject, id, req.Subject, service.Permission(req.Permission))
+	if err != nil {
+		writeTaskError(w, logEntry.WithField("task_id", id), err)
+		return
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"task_id":     id,
+		"shared_with": req.Subject,
+		"permission":  req.Permission,
+	}).Info("task shared successfully")
+
+	setETag(w, task)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toTaskResponse(task, loc))
+}
+
+// UnshareTask обрабатывает DELETE /v1/tasks/{id}/shares/{subject}
+func (h *TaskHandler) UnshareTask(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "UnshareTask",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	with := r.PathValue("subject")
+	if _, err := h.taskService.Unshare(r.Context(), identity.Subject, id, with); err != nil {
+		writeTaskError(w, logEntry.WithField("task_id", id), err)
+		return
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"task_id":     id,
+		"shared_with": with,
+	}).Info("task share revoked")
+	w.WriteHeader(http.StatusNoContent)
+}
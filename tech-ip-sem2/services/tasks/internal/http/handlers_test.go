@@ -0,0 +1,338 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/idempotency"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
+)
+
+// fakeVerifier принимает только токены из заранее заданного набора
+type fakeVerifier map[string]authclient.Identity
+
+func (f fakeVerifier) VerifyToken(_ context.Context, token string) (bool, authclient.Identity, error) {
+	id, ok := f[token]
+	return ok, id, nil
+}
+
+var (
+	readScopes  = []string{authclient.ScopeTasksRead}
+	writeScopes = []string{authclient.ScopeTasksRead, authclient.ScopeTasksWrite}
+	adminScopes = []string{authclient.ScopeTasksRead, authclient.ScopeTasksWrite, authclient.ScopeTasksAdmin}
+)
+
+// testAPI — REST API поверх хранилища в памяти с пользователями alice, bob и carol (user),
+// reader (только чтение) и admin
+type testAPI struct {
+	t           *testing.T
+	handler     http.Handler
+	tasks       *service.TaskService
+	idempotency *idempotency.Store
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	return newTestAPIWithIDs(t, service.UUIDv7IDs)
+}
+
+// newTestAPIWithIDs — testAPI, в котором идентификаторы задач выдаёт newID
+func newTestAPIWithIDs(t *testing.T, newID service.IDGenerator) *testAPI {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	ts := service.NewTaskService(service.NewMemoryTaskRepository(), newID, service.NewEventBus(service.DefaultEventHistory))
+	verifier := fakeVerifier{
+		"alice":  {Subject: "alice", Scopes: writeScopes},
+		"bob":    {Subject: "bob", Scopes: writeScopes},
+		"carol":  {Subject: "carol", Scopes: writeScopes},
+		"reader": {Subject: "reader", Scopes: readScopes},
+		"admin":  {Subject: "admin", Scopes: adminScopes},
+	}
+	keys := idempotency.NewStore(time.Hour)
+	h := NewTaskHandler(ts, verifier, keys, webhook.NewService(webhook.NewMemoryStore(), webhook.Guard{}, http.DefaultClient, webhook.DefaultRetryPolicy, logger), logger)
+
+	mux := http.NewServeMux()
+	h.Routes(mux)
+	return &testAPI{t: t, handler: mux, tasks: ts, idempotency: keys}
+}
+
+// do выполняет запрос от имени token; пустой token — запрос без Authorization
+func (a *testAPI) do(method, path, token, body string, header ...string) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, r)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	a.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// createTask создаёт задачу от имени token и возвращает её
+func (a *testAPI) createTask(token, body string) taskResponse {
+	a.t.Helper()
+	rec := a.do(http.MethodPost, "/v1/tasks", token, body)
+	if rec.Code != http.StatusCreated {
+		a.t.Fatalf("create task: status %d, body %s", rec.Code, rec.Body)
+	}
+	return decode[taskResponse](a.t, rec)
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return v
+}
+
+func TestRoutesRequireScopes(t *testing.T) {
+	api := newTestAPI(t)
+	task := api.createTask("alice", `{"title":"общая"}`)
+	if rec := api.do(http.MethodPost, "/v1/tasks/"+task.ID+"/shares", "alice", `{"subject":"reader","permission":"editor"}`); rec.Code != http.StatusOK {
+		t.Fatalf("share: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"reader lists tasks", http.MethodGet, "/v1/tasks", "reader", "", http.StatusOK},
+		{"reader reads shared task", http.MethodGet, "/v1/tasks/" + task.ID, "reader", "", http.StatusOK},
+		{"reader lists tags", http.MethodGet, "/v1/tags", "reader", "", http.StatusOK},
+		{"reader creates task", http.MethodPost, "/v1/tasks", "reader", `{"title":"x"}`, http.StatusForbidden},
+		// Право editor на задачу не заменяет scope tasks:write у токена
+		{"reader edits shared task", http.MethodPatch, "/v1/tasks/" + task.ID, "reader", `{"title":"x"}`, http.StatusForbidden},
+		{"reader replaces task", http.MethodPut, "/v1/tasks/" + task.ID, "reader", `{"title":"x"}`, http.StatusForbidden},
+		{"reader deletes task", http.MethodDelete, "/v1/tasks/" + task.ID, "reader", "", http.StatusForbidden},
+		{"reader runs batch", http.MethodPost, "/v1/tasks:batch", "reader", `{"operations":[]}`, http.StatusForbidden},
+		{"reader shares task", http.MethodPost, "/v1/tasks/" + task.ID + "/shares", "reader", `{"subject":"bob","permission":"viewer"}`, http.StatusForbidden},
+		{"reader renames tag", http.MethodPatch, "/v1/tags/work", "reader", `{"name":"job"}`, http.StatusForbidden},
+		{"reader merges tags", http.MethodPost, "/v1/tags:merge", "reader", `{"sources":["a"],"target":"b"}`, http.StatusForbidden},
+		{"reader creates webhook", http.MethodPost, "/v1/webhooks", "reader", `{"url":"https://example.com"}`, http.StatusForbidden},
+		{"reader deletes webhook", http.MethodDelete, "/v1/webhooks/wh_1", "reader", "", http.StatusForbidden},
+		{"missing token", http.MethodGet, "/v1/tasks", "", "", http.StatusUnauthorized},
+		{"unknown token", http.MethodPost, "/v1/tasks", "mallory", `{"title":"x"}`, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.do(tt.method, tt.path, tt.token, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	// Отказанные запросы ничего не изменили
+	got := decode[taskResponse](t, api.do(http.MethodGet, "/v1/tasks/"+task.ID, "alice", ""))
+	if got.Version != 2 || got.Title != "общая" {
+		t.Fatalf("task after refused writes: version %d, title %q", got.Version, got.Title)
+	}
+}
+
+func TestAdminScopeOpensForeignTasks(t *testing.T) {
+	api := newTestAPI(t)
+	task := api.createTask("alice", `{"title":"чужая"}`)
+
+	if rec := api.do(http.MethodGet, "/v1/tasks/"+task.ID, "bob", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("bob reads alice's task: status %d, want 404", rec.Code)
+	}
+	if rec := api.do(http.MethodGet, "/v1/tasks/"+task.ID, "admin", ""); rec.Code != http.StatusOK {
+		t.Fatalf("admin reads alice's task: status %d, body %s", rec.Code, rec.Body)
+	}
+	rec := api.do(http.MethodPatch, "/v1/tasks/"+task.ID, "admin", `{"done":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin updates alice's task: status %d, body %s", rec.Code, rec.Body)
+	}
+	if updated := decode[taskResponse](t, rec); updated.Owner != "alice" || !updated.Done {
+		t.Fatalf("updated task: owner %q, done %v", updated.Owner, updated.Done)
+	}
+
+	// Список задач администратора по-прежнему содержит только его задачи
+	list := decode[listTasksResponse](t, api.do(http.MethodGet, "/v1/tasks", "admin", ""))
+	if len(list.Items) != 0 {
+		t.Fatalf("admin list = %d tasks, want 0", len(list.Items))
+	}
+
+	if rec := api.do(http.MethodDelete, "/v1/tasks/"+task.ID, "admin", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("admin deletes alice's task: status %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+// TestTaskSharing проходит по шагам жизненный цикл доступа к задаче alice:
+// каждый шаг видит изменения предыдущих
+func TestTaskSharing(t *testing.T) {
+	api := newTestAPI(t)
+	task := api.createTask("alice", `{"title":"общая"}`)
+	path := "/v1/tasks/" + task.ID
+
+	steps := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		// Чужая задача неотличима от несуществующей
+		{"carol reads foreign task", http.MethodGet, path, "carol", "", http.StatusNotFound},
+		{"carol edits foreign task", http.MethodPatch, path, "carol", `{"title":"x"}`, http.StatusNotFound},
+		{"carol deletes foreign task", http.MethodDelete, path, "carol", "", http.StatusNotFound},
+		{"carol shares foreign task", http.MethodPost, path + "/shares", "carol", `{"subject":"carol","permission":"editor"}`, http.StatusNotFound},
+
+		{"alice shares with bob", http.MethodPost, path + "/shares", "alice", `{"subject":"bob","permission":"viewer"}`, http.StatusOK},
+		{"viewer reads", http.MethodGet, path, "bob", "", http.StatusOK},
+		{"viewer edits", http.MethodPatch, path, "bob", `{"title":"x"}`, http.StatusForbidden},
+		{"viewer replaces", http.MethodPut, path, "bob", `{"title":"x"}`, http.StatusForbidden},
+		{"viewer deletes", http.MethodDelete, path, "bob", "", http.StatusForbidden},
+		// Управлять доступом может только владелец
+		{"viewer shares further", http.MethodPost, path + "/shares", "bob", `{"subject":"carol","permission":"viewer"}`, http.StatusForbidden},
+		{"viewer unshares self", http.MethodDelete, path + "/shares/bob", "bob", "", http.StatusForbidden},
+
+		{"alice makes bob editor", http.MethodPost, path + "/shares", "alice", `{"subject":"bob","permission":"editor"}`, http.StatusOK},
+		{"editor edits", http.MethodPatch, path, "bob", `{"title":"от bob"}`, http.StatusOK},
+		{"editor deletes", http.MethodDelete, path, "bob", "", http.StatusForbidden},
+		{"editor shares further", http.MethodPost, path + "/shares", "bob", `{"subject":"carol","permission":"viewer"}`, http.StatusForbidden},
+		{"carol still has no access", http.MethodGet, path, "carol", "", http.StatusNotFound},
+
+		{"alice shares with herself", http.MethodPost, path + "/shares", "alice", `{"subject":"alice","permission":"viewer"}`, http.StatusBadRequest},
+		{"alice shares with bad permission", http.MethodPost, path + "/shares", "alice", `{"subject":"carol","permission":"owner"}`, http.StatusBadRequest},
+		{"alice unshares unknown subject", http.MethodDelete, path + "/shares/carol", "alice", "", http.StatusNotFound},
+		{"alice unshares bob", http.MethodDelete, path + "/shares/bob", "alice", "", http.StatusNoContent},
+		{"bob lost access", http.MethodGet, path, "bob", "", http.StatusNotFound},
+		{"bob cannot edit anymore", http.MethodPatch, path, "bob", `{"title":"x"}`, http.StatusNotFound},
+	}
+	for _, step := range steps {
+		rec := api.do(step.method, step.path, step.token, step.body)
+		if rec.Code != step.want {
+			t.Fatalf("%s: status = %d, want %d, body %s", step.name, rec.Code, step.want, rec.Body)
+		}
+	}
+
+	got := decode[taskResponse](t, api.do(http.MethodGet, path, "alice", ""))
+	if got.Title != "от bob" || len(got.Shares) != 0 {
+		t.Fatalf("task after sharing steps: title %q, shares %v", got.Title, got.Shares)
+	}
+}
+
+func TestSharedTaskInGranteeList(t *testing.T) {
+	api := newTestAPI(t)
+	shared := api.createTask("alice", `{"title":"общая"}`)
+	api.createTask("alice", `{"title":"личная"}`)
+	own := api.createTask("bob", `{"title":"своя"}`)
+	if rec := api.do(http.MethodPost, "/v1/tasks/"+shared.ID+"/shares", "alice", `{"subject":"bob","permission":"viewer"}`); rec.Code != http.StatusOK {
+		t.Fatalf("share: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		token string
+		want  []string
+	}{
+		{"bob", []string{own.ID, shared.ID}},
+		{"carol", nil},
+	}
+	for _, tt := range tests {
+		list := decode[listTasksResponse](t, api.do(http.MethodGet, "/v1/tasks", tt.token, ""))
+		var ids []string
+		for _, item := range list.Items {
+			ids = append(ids, item.ID)
+		}
+		slices.Sort(ids)
+		want := slices.Clone(tt.want)
+		slices.Sort(want)
+		if !slices.Equal(ids, want) {
+			t.Errorf("%s lists %v, want %v", tt.token, ids, want)
+		}
+	}
+}
+
+func TestDueDateTimezone(t *testing.T) {
+	api := newTestAPI(t)
+	moment := api.createTask("alice", `{"title":"созвон","due_date":"2026-03-01T18:00:00+03:00"}`)
+	date := api.createTask("alice", `{"title":"отчёт","due_date":"2026-03-01"}`)
+
+	tests := []struct {
+		name     string
+		timezone string
+		want     int
+		// wantMoment и wantDate — due_date задач moment и date в ответе
+		wantMoment string
+		wantDate   string
+	}{
+		{"default is UTC", "", http.StatusOK, "2026-03-01T15:00:00Z", "2026-03-01"},
+		{"IANA zone", "Asia/Tokyo", http.StatusOK, "2026-03-02T00:00:00+09:00", "2026-03-01"},
+		{"zone west of UTC", "America/New_York", http.StatusOK, "2026-03-01T10:00:00-05:00", "2026-03-01"},
+		{"unknown zone", "Mars/Olympus_Mons", http.StatusBadRequest, "", ""},
+		{"offset is not a zone name", "+03:00", http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header []string
+			if tt.timezone != "" {
+				header = []string{"X-Timezone", tt.timezone}
+			}
+			for id, want := range map[string]string{moment.ID: tt.wantMoment, date.ID: tt.wantDate} {
+				rec := api.do(http.MethodGet, "/v1/tasks/"+id, "alice", "", header...)
+				if rec.Code != tt.want {
+					t.Fatalf("GET: status %d, want %d, body %s", rec.Code, tt.want, rec.Body)
+				}
+				if tt.want != http.StatusOK {
+					continue
+				}
+				if got := decode[taskResponse](t, rec).DueDate; got != want {
+					t.Errorf("due_date = %q, want %q", got, want)
+				}
+			}
+		})
+	}
+
+	for _, due := range []string{"next friday", "2026-02-30", "2026-03-01T18:00:00"} {
+		rec := api.do(http.MethodPost, "/v1/tasks", "alice", `{"title":"x","due_date":"`+due+`"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("create with due_date %q: status %d, want %d", due, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestCreateWithTakenIDConflicts(t *testing.T) {
+	// Генератор всегда выдаёт один и тот же id: вторая задача не может его получить
+	api := newTestAPIWithIDs(t, func() (string, error) { return "t_1", nil })
+	if task := api.createTask("alice", `{"title":"первая"}`); task.ID != "t_1" {
+		t.Fatalf("first task id = %q, want t_1", task.ID)
+	}
+
+	rec := api.do(http.MethodPost, "/v1/tasks", "bob", `{"title":"вторая"}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("create with taken id: status %d, want %d, body %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+	if rec := api.do(http.MethodGet, "/v1/tasks/t_1", "alice", ""); rec.Code != http.StatusOK || decode[taskResponse](t, rec).Title != "первая" {
+		t.Fatalf("existing task after collision: status %d, body %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/idempotency"
+)
+
+const (
+	maxIdempotencyKeyLen = 255
+	maxRequestBody       = 1 << 20
+)
+
+type identityKey struct{}
+
+// withIdentity сохраняет в контексте владельца токена, проверенного Require
+func withIdentity(ctx context.Context, id authclient.Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+func identityFromContext(ctx context.Context) (authclient.Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(authclient.Identity)
+	return id, ok
+}
+
+// recorder пропускает ответ клиенту и одновременно запоминает его
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Idempotent добавляет изменяющему обработчику поддержку заголовка Idempotency-Key.
+// Первый ответ на ключ (кроме 5xx) сохраняется для пары subject + ключ и
+// возвращается на повторы; тот же ключ с другим запросом отклоняется с 422.
+// Оборачивается в Require: ключи хранятся отдельно для каждого владельца токена.
+func (h *TaskHandler) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		logEntry := h.logger.WithFields(logrus.Fields{
+			"component":  "http_handler",
+			"handler":    "Idempotent",
+			"request_id": middleware.GetRequestID(r.Context()),
+		})
+		if len(key) > maxIdempotencyKeyLen {
+			logEntry.Warn("idempotency key too long")
+			http.Error(w, `{"error":"idempotency key is too long"}`, http.StatusBadRequest)
+			return
+		}
+
+		identity, ok := h.identity(w, r)
+		if !ok {
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			logEntry.WithError(err).Warn("invalid request body")
+			http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(r.Method, r.URL.RequestURI(), r.Header.Get("If-Match"), body)
+
+		logEntry = logEntry.WithField("subject", identity.Subject)
+		outcome, stored := h.idempotency.Begin(identity.Subject, key, fingerprint, time.Now())
+		switch outcome {
+		case idempotency.Replay:
+			logEntry.Info("replaying stored response for idempotency key")
+			for name, values := range stored.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		case idempotency.Mismatch:
+			logEntry.Warn("idempotency key reused with a different request")
+			http.Error(w, `{"error":"idempotency key was used with a different request"}`, http.StatusUnprocessableEntity)
+			return
+		case idempotency.InProgress:
+			logEntry.Warn("request with the same idempotency key is in progress")
+			http.Error(w, `{"error":"request with this idempotency key is in progress"}`, http.StatusConflict)
+			return
+		}
+
+		// Ключ освобождается при любом исходе, кроме сохранённого ответа, в том числе
+		// при панике в next: иначе повторы получали бы 409 до истечения срока ключа
+		completed := false
+		defer func() {
+			if !completed {
+				h.idempotency.Abort(identity.Subject, key)
+			}
+		}()
+
+		rec := &recorder{ResponseWriter: w}
+		next(rec, r)
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+			return
+		}
+		h.idempotency.Complete(identity.Subject, key, idempotency.Response{
+			Status: rec.status,
+			Header: rec.header,
+			Body:   rec.body.Bytes(),
+		})
+		completed = true
+	}
+}
+
+// requestFingerprint — отпечаток запроса: повтор с тем же ключом должен совпадать с ним.
+// If-Match входит в отпечаток: с другим условием это уже другой запрос.
+func requestFingerprint(method, uri, ifMatch string, body []byte) string {
+	sum := sha256.New()
+	io.WriteString(sum, method+" "+uri+"\n"+ifMatch+"\n")
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
@@ -0,0 +1,231 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/idempotency"
+)
+
+// idemRequest — запрос теста идемпотентности; {id} в path заменяется id заранее созданной задачи
+type idemRequest struct {
+	token  string
+	method string
+	path   string
+	body   string
+	key    string
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second idemRequest
+		wantFirst     int
+		wantSecond    int
+		// replayed — второй ответ взят из сохранённого, а не получен повторным выполнением
+		replayed bool
+	}{
+		{
+			name:      "create is replayed",
+			first:     idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"один раз"}`, "k1"},
+			second:    idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"один раз"}`, "k1"},
+			wantFirst: http.StatusCreated, wantSecond: http.StatusCreated, replayed: true,
+		},
+		{
+			name:      "delete is replayed instead of 404",
+			first:     idemRequest{"alice", http.MethodDelete, "/v1/tasks/{id}", "", "k1"},
+			second:    idemRequest{"alice", http.MethodDelete, "/v1/tasks/{id}", "", "k1"},
+			wantFirst: http.StatusNoContent, wantSecond: http.StatusNoContent, replayed: true,
+		},
+		{
+			// Ответ 4xx окончателен и тоже сохраняется
+			name:      "client error is replayed",
+			first:     idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":""}`, "k1"},
+			second:    idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":""}`, "k1"},
+			wantFirst: http.StatusBadRequest, wantSecond: http.StatusBadRequest, replayed: true,
+		},
+		{
+			name:      "same key with different body",
+			first:     idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"первая"}`, "k1"},
+			second:    idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"вторая"}`, "k1"},
+			wantFirst: http.StatusCreated, wantSecond: http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "same key with different path",
+			first:     idemRequest{"alice", http.MethodPatch, "/v1/tasks/{id}", `{"done":true}`, "k1"},
+			second:    idemRequest{"alice", http.MethodPatch, "/v1/tasks/{id}?x=1", `{"done":true}`, "k1"},
+			wantFirst: http.StatusOK, wantSecond: http.StatusUnprocessableEntity,
+		},
+		{
+			// Ключи хранятся отдельно для каждого владельца токена
+			name:      "same key of another subject",
+			first:     idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"своя"}`, "k1"},
+			second:    idemRequest{"bob", http.MethodPost, "/v1/tasks", `{"title":"своя"}`, "k1"},
+			wantFirst: http.StatusCreated, wantSecond: http.StatusCreated,
+		},
+		{
+			name:      "different keys",
+			first:     idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"две"}`, "k1"},
+			second:    idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"две"}`, "k2"},
+			wantFirst: http.StatusCreated, wantSecond: http.StatusCreated,
+		},
+		{
+			name:      "without key",
+			first:     idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"две"}`, ""},
+			second:    idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"две"}`, ""},
+			wantFirst: http.StatusCreated, wantSecond: http.StatusCreated,
+		},
+		{
+			name:      "key too long",
+			first:     idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"x"}`, strings.Repeat("k", maxIdempotencyKeyLen+1)},
+			second:    idemRequest{"alice", http.MethodPost, "/v1/tasks", `{"title":"x"}`, "k1"},
+			wantFirst: http.StatusBadRequest, wantSecond: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			task := api.createTask("alice", `{"title":"существующая"}`)
+			send := func(req idemRequest) *http.Response {
+				var header []string
+				if req.key != "" {
+					header = []string{"Idempotency-Key", req.key}
+				}
+				path := strings.ReplaceAll(req.path, "{id}", task.ID)
+				return api.do(req.method, path, req.token, req.body, header...).Result()
+			}
+
+			first := send(tt.first)
+			if first.StatusCode != tt.wantFirst {
+				t.Fatalf("first status = %d, want %d", first.StatusCode, tt.wantFirst)
+			}
+			second := send(tt.second)
+			if second.StatusCode != tt.wantSecond {
+				t.Fatalf("second status = %d, want %d", second.StatusCode, tt.wantSecond)
+			}
+			if got := second.Header.Get("Idempotent-Replayed") == "true"; got != tt.replayed {
+				t.Fatalf("second response replayed = %v, want %v", got, tt.replayed)
+			}
+			if !tt.replayed {
+				return
+			}
+
+			// Повтор возвращает тот же ответ: тело и заголовки совпадают
+			firstBody, secondBody := readAll(t, first), readAll(t, second)
+			if firstBody != secondBody {
+				t.Fatalf("replayed body = %s, want %s", secondBody, firstBody)
+			}
+			for _, name := range []string{"Content-Type", "ETag", "Location"} {
+				if first.Header.Get(name) != second.Header.Get(name) {
+					t.Fatalf("replayed %s = %q, want %q", name, second.Header.Get(name), first.Header.Get(name))
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyReplayDoesNotRepeatChange(t *testing.T) {
+	api := newTestAPI(t)
+	for i := 0; i < 3; i++ {
+		if rec := api.do(http.MethodPost, "/v1/tasks", "alice", `{"title":"одна"}`, "Idempotency-Key", "create-1"); rec.Code != http.StatusCreated {
+			t.Fatalf("attempt %d: status %d", i+1, rec.Code)
+		}
+	}
+	list := decode[listTasksResponse](t, api.do(http.MethodGet, "/v1/tasks", "alice", ""))
+	if len(list.Items) != 1 {
+		t.Fatalf("tasks after 3 attempts = %d, want 1", len(list.Items))
+	}
+
+	task := list.Items[0]
+	for i := 0; i < 2; i++ {
+		rec := api.do(http.MethodPatch, "/v1/tasks/"+task.ID, "alice", `{"done":true}`, "Idempotency-Key", "patch-1", "If-Match", `"1"`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("attempt %d: status %d, body %s", i+1, rec.Code, rec.Body)
+		}
+	}
+	if got := decode[taskResponse](t, api.do(http.MethodGet, "/v1/tasks/"+task.ID, "alice", "")); got.Version != 2 {
+		t.Fatalf("version after replayed patch = %d, want 2", got.Version)
+	}
+}
+
+func TestIdempotencyKeyInProgress(t *testing.T) {
+	api := newTestAPI(t)
+	// Первый такой же запрос с ключом ещё выполняется
+	const body = `{"title":"x"}`
+	api.idempotency.Begin("alice", "busy", requestFingerprint(http.MethodPost, "/v1/tasks", "", []byte(body)), time.Now())
+
+	rec := api.do(http.MethodPost, "/v1/tasks", "alice", body, "Idempotency-Key", "busy")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	list := decode[listTasksResponse](t, api.do(http.MethodGet, "/v1/tasks", "alice", ""))
+	if len(list.Items) != 0 {
+		t.Fatalf("request in progress was executed again: %d tasks", len(list.Items))
+	}
+}
+
+func TestIdempotencyKeyWithDifferentPrecondition(t *testing.T) {
+	api := newTestAPI(t)
+	task := api.createTask("alice", `{"title":"x"}`)
+	path := "/v1/tasks/" + task.ID
+
+	rec := api.do(http.MethodPatch, path, "alice", `{"done":true}`, "Idempotency-Key", "k1", "If-Match", `"1"`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first status = %d, body %s", rec.Code, rec.Body)
+	}
+	// Тот же ключ и тело, но другое условие — это другой запрос, а не повтор
+	for _, ifMatch := range []string{`"2"`, ""} {
+		var header []string
+		if ifMatch != "" {
+			header = []string{"If-Match", ifMatch}
+		}
+		rec = api.do(http.MethodPatch, path, "alice", `{"done":true}`, append(header, "Idempotency-Key", "k1")...)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("retry with If-Match %q: status %d, want %d", ifMatch, rec.Code, http.StatusUnprocessableEntity)
+		}
+	}
+}
+
+func TestIdempotencyKeyReleasedAfterPanic(t *testing.T) {
+	api := newTestAPI(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	h := NewTaskHandler(nil, fakeVerifier{"alice": {Subject: "alice", Scopes: writeScopes}}, api.idempotency, nil, logger)
+	handler := h.Require(authclient.ScopeTasksWrite, h.Idempotent(func(http.ResponseWriter, *http.Request) {
+		panic("handler bug")
+	}))
+
+	const body = `{"title":"x"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/tasks", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer alice")
+	req.Header.Set("Idempotency-Key", "k1")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("handler did not panic")
+			}
+		}()
+		handler(httptest.NewRecorder(), req)
+	}()
+
+	// Ключ свободен: повтор выполняется заново, а не получает 409
+	if outcome, _ := api.idempotency.Begin("alice", "k1", requestFingerprint(http.MethodPost, "/v1/tasks", "", []byte(body)), time.Now()); outcome != idempotency.Started {
+		t.Fatalf("Begin after panic = %v, want %v", outcome, idempotency.Started)
+	}
+}
+
+func readAll(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := io.Copy(&b, resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
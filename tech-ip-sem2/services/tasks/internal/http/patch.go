@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+const (
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	errUnsupportedPatch = errors.New("unsupported patch content type")
+	errPatchConflict    = errors.New("patch test failed")
+)
+
+// patchError — патч некорректен или приводит задачу в недопустимое состояние
+type patchError struct {
+	msg string
+}
+
+func (e *patchError) Error() string {
+	return e.msg
+}
+
+func invalidPatch(format string, args ...any) error {
+	return &patchError{msg: fmt.Sprintf(format, args...)}
+}
+
+// taskDocument — изменяемая часть задачи в том виде, к которому применяются
+// PATCH и PUT. Отсутствующее поле и null означают значение по умолчанию:
+// пустое описание, отсутствие срока, done=false, без тегов. title обязателен.
+type taskDocument struct {
+	Title       *string  `json:"title"`
+	Description *string  `json:"description"`
+	DueDate     *string  `json:"due_date,omitempty"`
+	Done        *bool    `json:"done"`
+	Tags        []string `json:"tags"`
+}
+
+func toTaskDocument(t service.Task) taskDocument {
+	doc := taskDocument{
+		Title:       &t.Title,
+		Description: &t.Description,
+		Done:        &t.Done,
+		// Пустой массив, а не null: к нему применима операция JSON Patch add /tags/-
+		Tags: append([]string{}, t.Tags...),
+	}
+	if due := t.DueDate.String(); due != "" {
+		doc.DueDate = &due
+	}
+	return doc
+}
+
+// decodeTaskDocument строго разбирает документ: неизвестные и служебные поля
+// (id, owner, shares, ...) отклоняются
+func decodeTaskDocument(data []byte) (taskDocument, error) {
+	var doc taskDocument
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return taskDocument{}, invalidPatch("invalid task document: %v", err)
+	}
+	return doc, nil
+}
+
+// apply переносит поля документа в задачу
+func (d taskDocument) apply(t service.Task) (service.Task, error) {
+	if d.Title == nil || *d.Title == "" {
+		return service.Task{}, service.ErrTitleRequired
+	}
+	t.Title = *d.Title
+	t.Description = ""
+	if d.Description != nil {
+		t.Description = *d.Description
+	}
+	t.Done = d.Done != nil && *d.Done
+	t.Tags = d.Tags
+	switch {
+	case d.DueDate == nil:
+		t.DueDate = service.DueDate{}
+	case *d.DueDate == t.DueDate.String():
+		// Срок не менялся; старый срок в свободной форме сохраняется как есть
+	default:
+		due, err := service.ParseDueDate(*d.DueDate)
+		if err != nil {
+			return service.Task{}, invalidPatch("%v", err)
+		}
+		t.DueDate = due
+	}
+	return t, nil
+}
+
+// patchMediaType определяет формат патча по Content-Type. Обычный application/json
+// (и отсутствие заголовка) трактуется как JSON Merge Patch.
+func patchMediaType(contentType string) (string, error) {
+	if contentType == "" {
+		return contentTypeMergePatch, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", errUnsupportedPatch
+	}
+	switch mediaType {
+	case contentTypeMergePatch, "application/json":
+		return contentTypeMergePatch, nil
+	case contentTypeJSONPatch:
+		return contentTypeJSONPatch, nil
+	default:
+		return "", errUnsupportedPatch
+	}
+}
+
+// patchTask возвращает изменение для TaskService.Update, применяющее к задаче
+// JSON Merge Patch (RFC 7396) или JSON Patch (RFC 6902)
+func patchTask(mediaType string, body []byte) (func(service.Task) (service.Task, error), error) {
+	var applyPatch func(doc []byte) ([]byte, error)
+	switch mediaType {
+	case contentTypeJSONPatch:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, invalidPatch("invalid JSON Patch: %v", err)
+		}
+		applyPatch = patch.Apply
+	default:
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(body, &obj); err != nil {
+			return nil, invalidPatch("merge patch must be a JSON object")
+		}
+		applyPatch = func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, body)
+		}
+	}
+
+	return func(current service.Task) (service.Task, error) {
+		doc, err := json.Marshal(toTaskDocument(current))
+		if err != nil {
+			return service.Task{}, err
+		}
+		patched, err := applyPatch(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return service.Task{}, errPatchConflict
+		}
+		if err != nil {
+			return service.Task{}, invalidPatch("cannot apply patch: %v", err)
+		}
+		next, err := decodeTaskDocument(patched)
+		if err != nil {
+			return service.Task{}, err
+		}
+		return next.apply(current)
+	}, nil
+}
@@ -0,0 +1,205 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestUpdateTaskPatch(t *testing.T) {
+	const original = `{"title":"Исходная","description":"описание","due_date":"2026-03-01","tags":["home","work"]}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		// want проверяет задачу после успешного патча
+		want func(t *testing.T, got taskResponse)
+	}{
+		{
+			name:        "merge patch keeps absent fields",
+			contentType: contentTypeMergePatch,
+			body:        `{"title":"Новая"}`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Новая", "описание", "2026-03-01", false, "home", "work")
+			},
+		},
+		{
+			name:        "merge patch null deletes description",
+			contentType: contentTypeMergePatch,
+			body:        `{"description":null}`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Исходная", "", "2026-03-01", false, "home", "work")
+			},
+		},
+		{
+			name:        "merge patch null deletes due date and tags",
+			contentType: contentTypeMergePatch,
+			body:        `{"due_date":null,"tags":null,"done":true}`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Исходная", "описание", "", true)
+			},
+		},
+		{
+			name:        "merge patch replaces arrays whole",
+			contentType: contentTypeMergePatch,
+			body:        `{"tags":["urgent"]}`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Исходная", "описание", "2026-03-01", false, "urgent")
+			},
+		},
+		{
+			name:        "plain json is merge patch",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"due_date":null}`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Исходная", "описание", "", false, "home", "work")
+			},
+		},
+		{
+			name:        "merge patch null title",
+			contentType: contentTypeMergePatch,
+			body:        `{"title":null}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "merge patch service field",
+			contentType: contentTypeMergePatch,
+			body:        `{"owner":"bob"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "merge patch not an object",
+			contentType: contentTypeMergePatch,
+			body:        `[{"op":"remove","path":"/description"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "json patch replace",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"replace","path":"/title","value":"Новая"}]`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Новая", "описание", "2026-03-01", false, "home", "work")
+			},
+		},
+		{
+			name:        "json patch remove deletes due date",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"remove","path":"/due_date"}]`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Исходная", "описание", "", false, "home", "work")
+			},
+		},
+		{
+			// В JSON Patch null — обычное значение; для задачи оно означает значение по умолчанию
+			name:        "json patch null value resets description",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"replace","path":"/description","value":null}]`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Исходная", "", "2026-03-01", false, "home", "work")
+			},
+		},
+		{
+			name:        "json patch appends tag",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"add","path":"/tags/-","value":"urgent"}]`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Исходная", "описание", "2026-03-01", false, "home", "urgent", "work")
+			},
+		},
+		{
+			name:        "json patch passing test",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"test","path":"/title","value":"Исходная"},{"op":"replace","path":"/done","value":true}]`,
+			wantStatus:  http.StatusOK,
+			want: func(t *testing.T, got taskResponse) {
+				assertFields(t, got, "Исходная", "описание", "2026-03-01", true, "home", "work")
+			},
+		},
+		{
+			name:        "json patch failing test",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"test","path":"/title","value":"Другая"},{"op":"replace","path":"/done","value":true}]`,
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "json patch removes missing field",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"remove","path":"/nope"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "json patch removes title",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"remove","path":"/title"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "json patch invalid due date",
+			contentType: contentTypeJSONPatch,
+			body:        `[{"op":"replace","path":"/due_date","value":"завтра"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "json patch not an array",
+			contentType: contentTypeJSONPatch,
+			body:        `{"title":"Новая"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			body:        `title=Новая`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	api := newTestAPI(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := api.createTask("alice", original)
+			rec := api.do(http.MethodPatch, "/v1/tasks/"+task.ID, "alice", tt.body, "Content-Type", tt.contentType)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			stored := decode[taskResponse](t, api.do(http.MethodGet, "/v1/tasks/"+task.ID, "alice", ""))
+			if tt.want == nil {
+				// Отклонённый патч не меняет задачу
+				if stored.Version != task.Version {
+					t.Fatalf("rejected patch changed version %d -> %d", task.Version, stored.Version)
+				}
+				if tt.wantStatus == http.StatusUnsupportedMediaType && rec.Header().Get("Accept-Patch") == "" {
+					t.Fatal("415 without Accept-Patch")
+				}
+				return
+			}
+			got := decode[taskResponse](t, rec)
+			tt.want(t, got)
+			if got.Version != task.Version+1 || stored.Version != got.Version {
+				t.Fatalf("version = %d, stored %d, want %d", got.Version, stored.Version, task.Version+1)
+			}
+		})
+	}
+}
+
+func assertFields(t *testing.T, got taskResponse, title, description, due string, done bool, tags ...string) {
+	t.Helper()
+	if got.Title != title || got.Description != description || got.DueDate != due || got.Done != done {
+		t.Errorf("task = {title %q, description %q, due %q, done %v}, want {%q, %q, %q, %v}",
+			got.Title, got.Description, got.DueDate, got.Done, title, description, due, done)
+	}
+	if !slices.Equal(got.Tags, tags) {
+		t.Errorf("tags = %v, want %v", got.Tags, tags)
+	}
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// parseListQuery читает параметры GET /v1/tasks:
+// limit, cursor, done, due_before, due_after, q, sort, tag, tag_mode.
+// tag можно повторять или перечислять через запятую; tag_mode=all (по умолчанию)
+// выбирает задачи со всеми тегами, tag_mode=any — хотя бы с одним.
+func parseListQuery(r *http.Request) (service.ListQuery, error) {
+	values := r.URL.Query()
+	q := service.ListQuery{
+		Q:      values.Get("q"),
+		Sort:   values.Get("sort"),
+		Cursor: values.Get("cursor"),
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return q, fmt.Errorf("invalid limit %q", v)
+		}
+		q.Limit = limit
+	}
+	if v := values.Get("done"); v != "" {
+		done, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid done %q", v)
+		}
+		q.Done = &done
+	}
+	if v := values.Get("due_before"); v != "" {
+		t, err := service.ParseDueDate(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid due_before %q", v)
+		}
+		q.DueBefore = t.Time
+	}
+	if v := values.Get("due_after"); v != "" {
+		t, err := service.ParseDueDate(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid due_after %q", v)
+		}
+		q.DueAfter = t.Time
+	}
+	for _, v := range values["tag"] {
+		for _, tag := range strings.Split(v, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				q.Tags = append(q.Tags, tag)
+			}
+		}
+	}
+	switch mode := values.Get("tag_mode"); mode {
+	case "", "all":
+	case "any":
+		q.AnyTag = true
+	default:
+		return q, fmt.Errorf("invalid tag_mode %q: use all or any", mode)
+	}
+	return q, nil
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/client/authclient"
+)
+
+// Routes регистрирует маршруты REST API. Каждый маршрут объявляет scope,
+// которого требует от токена; tasks:admin дополнительно открывает доступ
+// к чужим задачам по id.
+func (h *TaskHandler) Routes(mux *http.ServeMux) {
+	read, write := authclient.ScopeTasksRead, authclient.ScopeTasksWrite
+
+	mux.HandleFunc("POST /v1/tasks", h.Require(write, h.Idempotent(h.CreateTask)))
+	mux.HandleFunc("POST /v1/tasks:batch", h.Require(write, h.Idempotent(h.BatchTasks)))
+	mux.HandleFunc("GET /v1/tasks", h.Require(read, h.ListTasks))
+	mux.HandleFunc("GET /v1/tasks/events", h.Require(read, h.WatchTasks))
+	mux.HandleFunc("GET /v1/tasks/{id}", h.Require(read, h.GetTask))
+	mux.HandleFunc("PATCH /v1/tasks/{id}", h.Require(write, h.Idempotent(h.UpdateTask)))
+	mux.HandleFunc("PUT /v1/tasks/{id}", h.Require(write, h.Idempotent(h.ReplaceTask)))
+	mux.HandleFunc("DELETE /v1/tasks/{id}", h.Require(write, h.Idempotent(h.DeleteTask)))
+	mux.HandleFunc("POST /v1/tasks/{id}/shares", h.Require(write, h.Idempotent(h.ShareTask)))
+	mux.HandleFunc("DELETE /v1/tasks/{id}/shares/{subject}", h.Require(write, h.Idempotent(h.UnshareTask)))
+	mux.HandleFunc("GET /v1/tags", h.Require(read, h.ListTags))
+	mux.HandleFunc("PATCH /v1/tags/{tag}", h.Require(write, h.Idempotent(h.RenameTag)))
+	mux.HandleFunc("POST /v1/tags:merge", h.Require(write, h.Idempotent(h.MergeTags)))
+	mux.HandleFunc("POST /v1/webhooks", h.Require(write, h.Idempotent(h.CreateWebhook)))
+	mux.HandleFunc("GET /v1/webhooks", h.Require(read, h.ListWebhooks))
+	mux.HandleFunc("DELETE /v1/webhooks/{id}", h.Require(write, h.Idempotent(h.DeleteWebhook)))
+	mux.HandleFunc("GET /v1/webhooks/{id}/deliveries", h.Require(read, h.ListWebhookDeliveries))
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+type tagResponse struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type renameTagRequest struct {
+	Name string `json:"name"`
+}
+
+type mergeTagsRequest struct {
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+}
+
+// retagResponse — итог переименования или объединения: итоговый тег и число изменённых задач
+type retagResponse struct {
+	Name    string `json:"name"`
+	Updated int    `json:"updated"`
+}
+
+// ListTags обрабатывает GET /v1/tags: теги собственных задач с числом задач
+func (h *TaskHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "ListTags",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := h.taskService.Tags(r.Context(), identity.Subject)
+	if err != nil {
+		writeTaskError(w, logEntry, err)
+		return
+	}
+	items := make([]tagResponse, 0, len(tags))
+	for _, t := range tags {
+		items = append(items, tagResponse{Name: t.Name, Count: t.Count})
+	}
+
+	logEntry.WithField("count", len(items)).Debug("tags listed")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// RenameTag обрабатывает PATCH /v1/tags/{tag}: переименование тега во всех собственных задачах
+func (h *TaskHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "RenameTag",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	tag := r.PathValue("tag")
+	var req renameTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logEntry.WithError(err).Warn("invalid request body")
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.taskService.RenameTag(r.Context(), identity.Subject, tag, req.Name)
+	if err != nil {
+		writeTaskError(w, logEntry.WithField("tag", tag), err)
+		return
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"tag":     tag,
+		"name":    req.Name,
+		"updated": updated,
+	}).Info("tag renamed successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	// Имя уже проверено сервисом, здесь оно только приводится к сохранённому виду
+	name, _ := service.NormalizeTag(req.Name)
+	json.NewEncoder(w).Encode(retagResponse{Name: name, Updated: updated})
+}
+
+// MergeTags обрабатывает POST /v1/tags:merge: теги sources заменяются тегом target
+// во всех собственных задачах
+func (h *TaskHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "MergeTags",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	var req mergeTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logEntry.WithError(err).Warn("invalid request body")
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.taskService.MergeTags(r.Context(), identity.Subject, req.Sources, req.Target)
+	if err != nil {
+		writeTaskError(w, logEntry.WithField("tag", req.Target), err)
+		return
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"sources": req.Sources,
+		"tag":     req.Target,
+		"updated": updated,
+	}).Info("tags merged successfully")
+
+	w.Header().Set("Content-Type", "application/json")
+	name, _ := service.NormalizeTag(req.Target)
+	json.NewEncoder(w).Encode(retagResponse{Name: name, Updated: updated})
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// listTags возвращает теги, которые видит token в GET /v1/tags
+func (a *testAPI) listTags(token string) []tagResponse {
+	a.t.Helper()
+	rec := a.do(http.MethodGet, "/v1/tags", token, "")
+	if rec.Code != http.StatusOK {
+		a.t.Fatalf("list tags: status %d, body %s", rec.Code, rec.Body)
+	}
+	return decode[struct {
+		Items []tagResponse `json:"items"`
+	}](a.t, rec).Items
+}
+
+func TestListTagsOwnTasksOnly(t *testing.T) {
+	api := newTestAPI(t)
+	shared := api.createTask("alice", `{"title":"общая","tags":["Work","home"]}`)
+	api.createTask("alice", `{"title":"вторая","tags":["work"]}`)
+	api.createTask("bob", `{"title":"своя","tags":["work","garden"]}`)
+	if rec := api.do(http.MethodPost, "/v1/tasks/"+shared.ID+"/shares", "alice", `{"subject":"bob","permission":"editor"}`); rec.Code != http.StatusOK {
+		t.Fatalf("share: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		token string
+		want  []tagResponse
+	}{
+		{"alice", []tagResponse{{"home", 1}, {"work", 2}}},
+		{"bob", []tagResponse{{"garden", 1}, {"work", 1}}},
+		{"carol", []tagResponse{}},
+	}
+	for _, tt := range tests {
+		if got := api.listTags(tt.token); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: tags = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	api := newTestAPI(t)
+	api.createTask("alice", `{"title":"первая","tags":["work","urgent"]}`)
+	api.createTask("alice", `{"title":"вторая","tags":["work"]}`)
+	api.createTask("bob", `{"title":"чужая","tags":["work"]}`)
+
+	tests := []struct {
+		name       string
+		tag, body  string
+		wantStatus int
+	}{
+		{"into existing tag", "work", `{"name":"Urgent"}`, http.StatusConflict},
+		{"missing tag", "missing", `{"name":"job"}`, http.StatusNotFound},
+		{"invalid name", "work", `{"name":"bad tag"}`, http.StatusBadRequest},
+		{"same name", "work", `{"name":"WORK"}`, http.StatusBadRequest},
+		{"invalid body", "work", `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := api.do(http.MethodPatch, "/v1/tags/"+tt.tag, "alice", tt.body); rec.Code != tt.wantStatus {
+			t.Errorf("%s: status %d, want %d, body %s", tt.name, rec.Code, tt.wantStatus, rec.Body)
+		}
+	}
+
+	rec := api.do(http.MethodPatch, "/v1/tags/Work", "alice", `{"name":" Job "}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rename: status %d, body %s", rec.Code, rec.Body)
+	}
+	if got := decode[retagResponse](t, rec); got != (retagResponse{Name: "job", Updated: 2}) {
+		t.Fatalf("rename response = %+v, want job, 2 updated", got)
+	}
+	if got, want := api.listTags("alice"), []tagResponse{{"job", 2}, {"urgent", 1}}; !slices.Equal(got, want) {
+		t.Errorf("alice's tags = %v, want %v", got, want)
+	}
+	if got, want := api.listTags("bob"), []tagResponse{{"work", 1}}; !slices.Equal(got, want) {
+		t.Errorf("bob's tags = %v, want %v", got, want)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	api := newTestAPI(t)
+	api.createTask("alice", `{"title":"обе","tags":["todo","backlog"]}`)
+	api.createTask("alice", `{"title":"цель","tags":["todo"]}`)
+	api.createTask("alice", `{"title":"источник","tags":["later"]}`)
+	api.createTask("bob", `{"title":"чужая","tags":["later"]}`)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"no sources", `{"sources":[],"target":"todo"}`, http.StatusBadRequest},
+		{"missing sources", `{"sources":["missing"],"target":"todo"}`, http.StatusNotFound},
+		{"invalid target", `{"sources":["later"],"target":"-todo"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := api.do(http.MethodPost, "/v1/tags:merge", "alice", tt.body); rec.Code != tt.wantStatus {
+			t.Errorf("%s: status %d, want %d, body %s", tt.name, rec.Code, tt.wantStatus, rec.Body)
+		}
+	}
+
+	// Задача только с целью не изменилась и в updated не входит
+	rec := api.do(http.MethodPost, "/v1/tags:merge", "alice", `{"sources":["Backlog","later"],"target":"TODO"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("merge: status %d, body %s", rec.Code, rec.Body)
+	}
+	if got := decode[retagResponse](t, rec); got != (retagResponse{Name: "todo", Updated: 2}) {
+		t.Fatalf("merge response = %+v, want todo, 2 updated", got)
+	}
+	if got, want := api.listTags("alice"), []tagResponse{{"todo", 3}}; !slices.Equal(got, want) {
+		t.Errorf("alice's tags = %v, want %v", got, want)
+	}
+	if got, want := api.listTags("bob"), []tagResponse{{"later", 1}}; !slices.Equal(got, want) {
+		t.Errorf("bob's tags = %v, want %v", got, want)
+	}
+}
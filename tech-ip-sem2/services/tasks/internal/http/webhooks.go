@@ -0,0 +1,222 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/shared/middleware"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
+)
+
+type createWebhookRequest struct {
+	URL    string              `json:"url"`
+	Events []service.EventType `json:"events"`
+	Secret string              `json:"secret"`
+}
+
+// webhookResponse — вебхук в ответе; секрет возвращается только при создании
+type webhookResponse struct {
+	ID        string              `json:"id"`
+	URL       string              `json:"url"`
+	Events    []service.EventType `json:"events"`
+	Secret    string              `json:"secret,omitempty"`
+	CreatedAt string              `json:"created_at"`
+}
+
+type deliveryResponse struct {
+	ID         string            `json:"id"`
+	EventID    string            `json:"event_id"`
+	Event      service.EventType `json:"event"`
+	TaskID     string            `json:"task_id"`
+	Attempt    int               `json:"attempt"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	At         string            `json:"at"`
+	Success    bool              `json:"success"`
+}
+
+func toWebhookResponse(hook webhook.Webhook, loc *time.Location) webhookResponse {
+	return webhookResponse{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    hook.Events,
+		CreatedAt: hook.CreatedAt.In(loc).Format(time.RFC3339),
+	}
+}
+
+// writeWebhookError переводит ошибки webhook.Service в HTTP-ответы
+func writeWebhookError(w http.ResponseWriter, logEntry *logrus.Entry, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		logEntry.Warn("webhook not found")
+		http.Error(w, `{"error":"webhook not found"}`, http.StatusNotFound)
+	case errors.Is(err, webhook.ErrInvalidWebhook):
+		logEntry.WithError(err).Warn("invalid webhook")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logEntry.WithError(err).Error("webhook operation failed")
+		http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+	}
+}
+
+// CreateWebhook обрабатывает POST /v1/webhooks
+func (h *TaskHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "CreateWebhook",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logEntry.WithError(err).Warn("invalid request body")
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	hook, err := h.webhooks.Register(r.Context(), identity.Subject, req.URL, req.Events, req.Secret)
+	if err != nil {
+		writeWebhookError(w, logEntry, err)
+		return
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"webhook_id": hook.ID,
+		"url":        hook.URL,
+		"events":     hook.Events,
+	}).Info("webhook created successfully")
+
+	resp := toWebhookResponse(hook, loc)
+	resp.Secret = hook.Secret
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListWebhooks обрабатывает GET /v1/webhooks
+func (h *TaskHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "ListWebhooks",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hooks, err := h.webhooks.List(r.Context(), identity.Subject)
+	if err != nil {
+		writeWebhookError(w, logEntry, err)
+		return
+	}
+	items := make([]webhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		items = append(items, toWebhookResponse(hook, loc))
+	}
+
+	logEntry.WithField("count", len(items)).Info("webhooks listed successfully")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
+
+// DeleteWebhook обрабатывает DELETE /v1/webhooks/{id}
+func (h *TaskHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "DeleteWebhook",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := h.webhooks.Delete(r.Context(), identity.Subject, id); err != nil {
+		writeWebhookError(w, logEntry.WithField("webhook_id", id), err)
+		return
+	}
+
+	logEntry.WithField("webhook_id", id).Info("webhook deleted successfully")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries обрабатывает GET /v1/webhooks/{id}/deliveries: последние
+// попытки доставки, начиная с самой свежей
+func (h *TaskHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	logEntry := h.logger.WithFields(logrus.Fields{
+		"component":  "http_handler",
+		"handler":    "ListWebhookDeliveries",
+		"request_id": requestID,
+	})
+
+	identity, ok := h.identity(w, r)
+	if !ok {
+		return
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		logEntry.WithError(err).Warn("invalid timezone")
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	deliveries, err := h.webhooks.Deliveries(r.Context(), identity.Subject, id)
+	if err != nil {
+		writeWebhookError(w, logEntry.WithField("webhook_id", id), err)
+		return
+	}
+
+	items := make([]deliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		items = append(items, deliveryResponse{
+			ID:         d.ID,
+			EventID:    d.EventKey,
+			Event:      d.EventType,
+			TaskID:     d.TaskID,
+			Attempt:    d.Attempt,
+			StatusCode: d.StatusCode,
+			Error:      d.Error,
+			DurationMs: d.Duration.Milliseconds(),
+			At:         d.At.In(loc).Format(time.RFC3339),
+			Success:    d.Success,
+		})
+	}
+
+	logEntry.WithFields(logrus.Fields{
+		"webhook_id": id,
+		"count":      len(items),
+	}).Info("webhook deliveries listed successfully")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items})
+}
@@ -0,0 +1,109 @@
+package idempotency
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Outcome — результат попытки начать запрос с ключом идемпотентности
+type Outcome int
+
+const (
+	// Started — ключ встретился впервые, запрос нужно выполнить и вызвать Complete или Abort
+	Started Outcome = iota
+	// Replay — запрос уже выполнен, нужно вернуть сохранённый ответ
+	Replay
+	// Mismatch — ключ уже использован для другого запроса
+	Mismatch
+	// InProgress — запрос с этим ключом ещё выполняется
+	InProgress
+)
+
+// Response — сохранённый ответ на первый запрос с ключом
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type entry struct {
+	fingerprint string
+	response    *Response
+	expiresAt   time.Time
+}
+
+// Store хранит ответы по паре subject + ключ в течение ttl
+type Store struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*entry
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		ttl:     ttl,
+		entries: make(map[string]*entry),
+	}
+}
+
+func entryKey(subject, key string) string {
+	return subject + "\x00" + key
+}
+
+// Begin регистрирует запрос. fingerprint описывает сам запрос (метод, путь, тело):
+// повтор с тем же ключом, но другим запросом возвращает Mismatch.
+func (s *Store) Begin(subject, key, fingerprint string, now time.Time) (Outcome, *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := entryKey(subject, key)
+	e, ok := s.entries[k]
+	if ok && now.After(e.expiresAt) {
+		delete(s.entries, k)
+		ok = false
+	}
+	if !ok {
+		s.entries[k] = &entry{fingerprint: fingerprint, expiresAt: now.Add(s.ttl)}
+		return Started, nil
+	}
+
+	switch {
+	case e.fingerprint != fingerprint:
+		return Mismatch, nil
+	case e.response == nil:
+		return InProgress, nil
+	default:
+		return Replay, e.response
+	}
+}
+
+// Complete сохраняет ответ на запрос, начатый Begin
+func (s *Store) Complete(subject, key string, resp Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[entryKey(subject, key)]; ok {
+		e.response = &resp
+	}
+}
+
+// Abort забывает ключ, чтобы запрос можно было повторить (например, после ошибки 5xx)
+func (s *Store) Abort(subject, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, entryKey(subject, key))
+}
+
+// PruneExpired удаляет истёкшие ключи и возвращает их число
+func (s *Store) PruneExpired(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for k, e := range s.entries {
+		if now.After(e.expiresAt) {
+			delete(s.entries, k)
+			n++
+		}
+	}
+	return n
+}
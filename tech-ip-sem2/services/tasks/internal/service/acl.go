@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+// Permission — уровень доступа, который владелец выдаёт другому пользователю
+type Permission string
+
+const (
+	PermissionViewer Permission = "viewer"
+	PermissionEditor Permission = "editor"
+)
+
+var (
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrForbidden     = errors.New("forbidden")
+	ErrShareNotFound = errors.New("share not found")
+	ErrInvalidShare  = errors.New("invalid share")
+)
+
+func (p Permission) Valid() bool {
+	return p == PermissionViewer || p == PermissionEditor
+}
+
+// CanView: владелец и все, с кем поделились задачей
+func (t Task) CanView(subject string) bool {
+	if t.Owner == subject {
+		return true
+	}
+	_, ok := t.Shares[subject]
+	return ok
+}
+
+// CanEdit: владелец и редакторы
+func (t Task) CanEdit(subject string) bool {
+	return t.Owner == subject || t.Shares[subject] == PermissionEditor
+}
+
+// CanManage: удалять задачу и управлять доступом может только владелец
+func (t Task) CanManage(subject string) bool {
+	return t.Owner == subject
+}
+
+type adminKey struct{}
+
+// WithAdmin помечает запрос администратора (scope tasks:admin): ему доступна
+// любая задача по id — чтение, изменение, удаление и управление доступом.
+// Списки задач, теги и поток событий остаются ограничены его собственными задачами.
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+func isAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
@@ -0,0 +1,37 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+func TestTaskPermissions(t *testing.T) {
+	task := service.Task{
+		Owner:  "alice",
+		Shares: map[string]service.Permission{"bob": service.PermissionViewer, "carol": service.PermissionEditor},
+	}
+	tests := []struct {
+		subject                     string
+		wantView, wantEdit, wantMgr bool
+	}{
+		{"alice", true, true, true},
+		{"bob", true, false, false},
+		{"carol", true, true, false},
+		{"dave", false, false, false},
+		{"", false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			if got := task.CanView(tt.subject); got != tt.wantView {
+				t.Errorf("CanView = %v, want %v", got, tt.wantView)
+			}
+			if got := task.CanEdit(tt.subject); got != tt.wantEdit {
+				t.Errorf("CanEdit = %v, want %v", got, tt.wantEdit)
+			}
+			if got := task.CanManage(tt.subject); got != tt.wantMgr {
+				t.Errorf("CanManage = %v, want %v", got, tt.wantMgr)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MaxBatchSize — наибольшее число операций в одном пакете
+const MaxBatchSize = 100
+
+// Виды операций пакета
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+var (
+	ErrInvalidBatch = errors.New("invalid batch")
+	// ErrBatchAborted — операция атомарного пакета не применена из-за ошибки в другой операции
+	ErrBatchAborted = errors.New("batch aborted")
+)
+
+// BatchOp — операция пакета. Для create заполняется Task, для update — ID и Change,
+// для delete — ID. IfMatch действует так же, как в Update и Delete.
+type BatchOp struct {
+	Kind    string
+	ID      string
+	Task    Task
+	IfMatch IfMatch
+	Change  func(Task) (Task, error)
+}
+
+// BatchResult — итог операции: задача после create или update либо ошибка
+type BatchResult struct {
+	Task Task
+	Err  error
+}
+
+// Batch выполняет операции по порядку от имени subject. В атомарном режиме все
+// операции идут в одной транзакции репозитория: при первой ошибке не сохраняется
+// ни одна, а остальные получают ErrBatchAborted. Иначе операции независимы.
+// Ошибка самого Batch означает некорректный пакет или сбой транзакции.
+func (s *TaskService) Batch(ctx context.Context, subject string, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	if len(ops) == 0 || len(ops) > MaxBatchSize {
+		return nil, fmt.Errorf("%w: batch must contain from 1 to %d operations", ErrInvalidBatch, MaxBatchSize)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]BatchResult, len(ops))
+	if !atomic {
+		for i, op := range ops {
+			err := s.commit(ctx, func(tx TaskRepository) (pendingEvent, error) {
+				var ev pendingEvent
+				results[i], ev = s.batchOp(ctx, tx, subject, op)
+				return ev, results[i].Err
+			})
+			if err != nil && results[i].Err == nil {
+				results[i] = BatchResult{Err: err}
+			}
+		}
+		return results, nil
+	}
+
+	failed := -1
+	err := s.repo.InTx(ctx, func(tx TaskRepository) error {
+		for i, op := range ops {
+			var ev pendingEvent
+			results[i], ev = s.batchOp(ctx, tx, subject, op)
+			if results[i].Err != nil {
+				failed = i
+				return results[i].Err
+			}
+			if err := ev.append(ctx, tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if failed < 0 {
+		if err != nil {
+			return nil, err
+		}
+		s.outboxReady()
+		return results, nil
+	}
+	for i := range results {
+		if i != failed {
+			results[i] = BatchResult{Err: ErrBatchAborted}
+		}
+	}
+	return results, nil
+}
+
+func (s *TaskService) batchOp(ctx context.Context, repo TaskRepository, subject string, op BatchOp) (BatchResult, pendingEvent) {
+	var res BatchResult
+	var ev pendingEvent
+	switch op.Kind {
+	case BatchCreate:
+		op.Task.Owner = subject
+		res.Task, res.Err = s.create(ctx, repo, op.Task)
+		ev = pendingEvent{typ: EventCreated, task: res.Task}
+	case BatchUpdate:
+		var before Task
+		before, res.Task, res.Err = s.update(ctx, repo, subject, op.ID, op.IfMatch, op.Change)
+		ev = pendingEvent{typ: EventUpdated, before: &before, task: res.Task}
+	case BatchDelete:
+		var deleted Task
+		deleted, res.Err = s.delete(ctx, repo, subject, op.ID, op.IfMatch)
+		ev = pendingEvent{typ: EventDeleted, task: deleted}
+	default:
+		res.Err = fmt.Errorf("%w: unknown operation %q", ErrInvalidBatch, op.Kind)
+	}
+	return res, ev
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidDueDate = errors.New("invalid due_date")
+
+// DueDate — срок задачи: момент времени (RFC 3339) или календарная дата без времени.
+// Нулевое значение означает, что срок не задан.
+type DueDate struct {
+	// Time — момент срока; для даты без времени — полночь по UTC
+	Time     time.Time
+	DateOnly bool
+	// legacy — срок в свободной форме («next friday»), сохранённый до появления
+	// проверки формата. Он хранится и отдаётся как есть, но момента времени у него
+	// нет: в сортировке и фильтрах такой срок считается незаданным.
+	legacy string
+}
+
+// ParseDueDate разбирает срок в формате RFC 3339 (2026-03-01T18:00:00+03:00)
+// или дату без времени (2026-03-01)
+func ParseDueDate(s string) (DueDate, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return DueDate{Time: t}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return DueDate{Time: t, DateOnly: true}, nil
+	}
+	return DueDate{}, fmt.Errorf("%w: %q is neither RFC 3339 nor YYYY-MM-DD", ErrInvalidDueDate, s)
+}
+
+// IsZero сообщает, что у срока нет момента времени: срок не задан или хранится в свободной форме
+func (d DueDate) IsZero() bool {
+	return d.Time.IsZero()
+}
+
+// Before сравнивает сроки как моменты времени
+func (d DueDate) Before(o DueDate) bool {
+	return d.Time.Before(o.Time)
+}
+
+func (d DueDate) Equal(o DueDate) bool {
+	return d.DateOnly == o.DateOnly && d.Time.Equal(o.Time) && d.legacy == o.legacy
+}
+
+// String возвращает срок в каноническом виде: дату как есть, момент времени — в UTC
+func (d DueDate) String() string {
+	return d.Format(time.UTC)
+}
+
+// Format представляет срок в часовом поясе loc. Дата без времени и срок
+// в свободной форме от пояса не зависят.
+func (d DueDate) Format(loc *time.Location) string {
+	switch {
+	case d.legacy != "":
+		return d.legacy
+	case d.IsZero():
+		return ""
+	case d.DateOnly:
+		return d.Time.Format(time.DateOnly)
+	default:
+		return d.Time.In(loc).Format(time.RFC3339)
+	}
+}
+
+func (d DueDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *DueDate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.parseStored(s)
+}
+
+// Value сохраняет срок в базе строкой; пустая строка — срок не задан
+func (d DueDate) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *DueDate) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = DueDate{}
+		return nil
+	case string:
+		return d.parseStored(v)
+	case []byte:
+		return d.parseStored(string(v))
+	default:
+		return fmt.Errorf("unsupported due_date type %T", src)
+	}
+}
+
+// parseStored читает сохранённый срок. Значение, которое не разбирается,
+// остаётся сроком в свободной форме: строки из старых версий не должны
+// делать задачу нечитаемой, а при повторном сохранении не теряются.
+func (d *DueDate) parseStored(s string) error {
+	if s == "" {
+		*d = DueDate{}
+		return nil
+	}
+	parsed, err := ParseDueDate(s)
+	if err != nil {
+		*d = DueDate{legacy: s}
+		return nil
+	}
+	*d = parsed
+	return nil
+}
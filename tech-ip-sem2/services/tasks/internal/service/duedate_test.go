@@ -0,0 +1,92 @@
+package service_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+func TestParseDueDate(t *testing.T) {
+	tests := []struct {
+		in           string
+		wantTime     time.Time
+		wantDateOnly bool
+		wantErr      error
+	}{
+		{in: "2026-03-01", wantTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), wantDateOnly: true},
+		{in: "2026-03-01T18:00:00Z", wantTime: time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)},
+		{in: "2026-03-01T18:00:00+03:00", wantTime: time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)},
+		{in: "2026-03-01T18:00:00.5-02:00", wantTime: time.Date(2026, 3, 1, 20, 0, 0, 5e8, time.UTC)},
+		{in: "", wantErr: service.ErrInvalidDueDate},
+		{in: "next friday", wantErr: service.ErrInvalidDueDate},
+		{in: "2026-02-30", wantErr: service.ErrInvalidDueDate},
+		{in: "01.03.2026", wantErr: service.ErrInvalidDueDate},
+		{in: "2026-03-01 18:00:00", wantErr: service.ErrInvalidDueDate},
+		{in: "2026-03-01T18:00:00", wantErr: service.ErrInvalidDueDate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := service.ParseDueDate(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseDueDate err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !got.Time.Equal(tt.wantTime) || got.DateOnly != tt.wantDateOnly {
+				t.Fatalf("ParseDueDate = %v (date only %v), want %v (date only %v)", got.Time, got.DateOnly, tt.wantTime, tt.wantDateOnly)
+			}
+		})
+	}
+}
+
+func TestDueDateFormat(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"2026-03-01", "2026-03-01"},
+		{"2026-03-01T12:00:00Z", "2026-03-01T15:00:00+03:00"},
+		{"2026-03-01T23:00:00Z", "2026-03-02T02:00:00+03:00"},
+	}
+	for _, tt := range tests {
+		due, err := service.ParseDueDate(tt.in)
+		if err != nil {
+			t.Fatalf("ParseDueDate(%q): %v", tt.in, err)
+		}
+		if got := due.Format(moscow); got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// Сроки в свободной форме, сохранённые старыми версиями, читаются без ошибки
+// и записываются обратно без изменений
+func TestDueDateKeepsLegacyValue(t *testing.T) {
+	var fromJSON service.DueDate
+	if err := json.Unmarshal([]byte(`"next friday"`), &fromJSON); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	var fromDB service.DueDate
+	if err := fromDB.Scan([]byte("next friday")); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	for _, due := range []service.DueDate{fromJSON, fromDB} {
+		if !due.IsZero() {
+			t.Errorf("legacy due date has a moment: %v", due.Time)
+		}
+		if got := due.Format(time.UTC); got != "next friday" {
+			t.Errorf("Format = %q, want the stored value", got)
+		}
+		if v, err := due.Value(); err != nil || v != "next friday" {
+			t.Errorf("Value = %v, %v; want the stored value", v, err)
+		}
+	}
+	if !fromJSON.Equal(fromDB) {
+		t.Error("equal legacy values compare as different")
+	}
+}
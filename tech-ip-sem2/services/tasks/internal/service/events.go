@@ -0,0 +1,166 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType — вид изменения задачи
+type EventType string
+
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// DefaultEventHistory — сколько последних событий хранится для возобновления подписки
+const DefaultEventHistory = 1024
+
+// subscriberBuffer — сколько событий может ждать доставки одному подписчику
+const subscriberBuffer = 64
+
+// Event — изменение задачи. Для deleted Task — последнее состояние перед удалением.
+type Event struct {
+	// ID — номер события в шине, по нему подписка возобновляется после обрыва
+	ID uint64
+	// Key — ключ дедупликации из outbox, одинаковый при повторной публикации
+	Key        string
+	Type       EventType
+	Task       Task
+	OccurredAt time.Time
+	// before — состояние до изменения; по нему видно, кто получил или потерял доступ к задаче
+	before *Task
+}
+
+// ForSubject возвращает событие таким, каким его видит subject. Чужие задачи
+// subject не видит, а получение и потерю доступа видит как создание и удаление задачи.
+func (e Event) ForSubject(subject string) (Event, bool) {
+	visible := e.Task.CanView(subject)
+	if e.Type != EventUpdated || e.before == nil {
+		return e, visible
+	}
+	switch wasVisible := e.before.CanView(subject); {
+	case visible && !wasVisible:
+		e.Type = EventCreated
+	case !visible && wasVisible:
+		e.Type = EventDeleted
+		e.Task = *e.before
+	case !visible:
+		return Event{}, false
+	}
+	return e, true
+}
+
+// EventBus рассылает события об изменениях задач подписчикам и хранит
+// последние события, чтобы переподключившийся подписчик получил пропущенное
+type EventBus struct {
+	mu      sync.Mutex
+	lastID  uint64
+	size    int
+	history []Event
+	// keys — ключи событий из history: повторно опубликованное событие не рассылается
+	keys map[string]struct{}
+	subs map[*Subscription]struct{}
+}
+
+// NewEventBus создаёт шину, хранящую последние size событий. Нумерация начинается
+// с текущего времени в микросекундах, поэтому id не повторяются после перезапуска
+// и id из прошлого запуска не примут за свежий.
+func NewEventBus(size int) *EventBus {
+	return &EventBus{
+		lastID: uint64(time.Now().UnixMicro()),
+		size:   size,
+		keys:   make(map[string]struct{}),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *EventBus) publish(oe OutboxEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.keys[oe.Key]; ok {
+		return
+	}
+	b.lastID++
+	ev := oe.event()
+	ev.ID = b.lastID
+	b.history = append(b.history, ev)
+	b.keys[ev.Key] = struct{}{}
+	if len(b.history) > b.size {
+		for _, old := range b.history[:len(b.history)-b.size] {
+			delete(b.keys, old.Key)
+		}
+		b.history = b.history[len(b.history)-b.size:]
+	}
+
+	for sub := range b.subs {
+		e, ok := ev.ForSubject(sub.subject)
+		if !ok {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// Подписчик не успевает: отключаем его, он может вернуться с последним полученным id
+			b.dropLocked(sub)
+		}
+	}
+}
+
+// Subscribe подписывает subject на события, следующие за событием after
+// (0 — только новые), и возвращает уже случившиеся из них. resumed=false значит,
+// что часть событий после after не сохранилась и состояние задач нужно перечитать.
+func (b *EventBus) Subscribe(subject string, after uint64) (sub *Subscription, missed []Event, resumed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	resumed = true
+	if after != 0 {
+		// История содержит события с id подряд: от lastID-len(history)+1 до lastID
+		first := b.lastID - uint64(len(b.history))
+		if after < first || after > b.lastID {
+			resumed = false
+		} else {
+			for _, ev := range b.history {
+				if ev.ID <= after {
+					continue
+				}
+				if e, ok := ev.ForSubject(subject); ok {
+					missed = append(missed, e)
+				}
+			}
+		}
+	}
+
+	sub = &Subscription{bus: b, subject: subject, ch: make(chan Event, subscriberBuffer)}
+	b.subs[sub] = struct{}{}
+	return sub, missed, resumed
+}
+
+func (b *EventBus) dropLocked(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscription — подписка на события задач, видимых subject
+type Subscription struct {
+	bus     *EventBus
+	subject string
+	ch      chan Event
+}
+
+// Events возвращает канал новых событий. Канал закрывается после Close
+// или если подписчик не успевает забирать события.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.dropLocked(s)
+}
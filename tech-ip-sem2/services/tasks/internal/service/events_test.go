@@ -0,0 +1,214 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// watchedService — TaskService, события которого сразу публикуются в шину
+type watchedService struct {
+	t   *testing.T
+	svc *service.TaskService
+	bus *service.EventBus
+}
+
+func newWatchedService(t *testing.T, history int) *watchedService {
+	bus := service.NewEventBus(history)
+	return &watchedService{t: t, svc: service.NewTaskService(service.NewMemoryTaskRepository(), service.UUIDv7IDs, bus), bus: bus}
+}
+
+// drain публикует события outbox в шину, как это делает RunOutbox
+func (w *watchedService) drain() {
+	w.t.Helper()
+	if _, err := w.svc.DrainOutbox(context.Background()); err != nil {
+		w.t.Fatalf("DrainOutbox: %v", err)
+	}
+}
+
+func (w *watchedService) create(owner, title string) service.Task {
+	w.t.Helper()
+	task, err := w.svc.Create(context.Background(), service.Task{Owner: owner, Title: title})
+	if err != nil {
+		w.t.Fatalf("Create: %v", err)
+	}
+	w.drain()
+	return task
+}
+
+// next возвращает следующее событие подписки или завершает тест, если его нет
+func next(t *testing.T, sub *service.Subscription) service.Event {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.Events():
+		if !ok {
+			t.Fatal("subscription is closed")
+		}
+		return ev
+	default:
+		t.Fatal("no event delivered")
+		return service.Event{}
+	}
+}
+
+// noEvent проверяет, что подписке ничего не доставлено
+func noEvent(t *testing.T, sub *service.Subscription) {
+	t.Helper()
+	select {
+	case ev := <-sub.Events():
+		t.Fatalf("unexpected event %s of task %s", ev.Type, ev.Task.ID)
+	default:
+	}
+}
+
+func TestEventBusResume(t *testing.T) {
+	tests := []struct {
+		name string
+		// after выбирает событие, после которого продолжается подписка, из четырёх опубликованных
+		after func(events []service.Event) uint64
+		// wantMissed — сколько последних событий вернёт Subscribe
+		wantMissed  int
+		wantResumed bool
+	}{
+		{"only new events", func([]service.Event) uint64 { return 0 }, 0, true},
+		// История хранит три события: 2–4. Продолжить можно с любого из них и с события 1
+		{"after event before history", func(ev []service.Event) uint64 { return ev[0].ID }, 3, true},
+		{"after kept event", func(ev []service.Event) uint64 { return ev[2].ID }, 1, true},
+		{"after last event", func(ev []service.Event) uint64 { return ev[3].ID }, 0, true},
+		{"history exhausted", func(ev []service.Event) uint64 { return ev[0].ID - 1 }, 0, false},
+		{"id from the future", func(ev []service.Event) uint64 { return ev[3].ID + 1 }, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWatchedService(t, 3)
+			live, _, _ := w.bus.Subscribe("alice", 0)
+			defer live.Close()
+			var events []service.Event
+			for _, title := range []string{"1", "2", "3", "4"} {
+				w.create("alice", title)
+				events = append(events, next(t, live))
+			}
+			for i := 1; i < len(events); i++ {
+				if events[i].ID != events[i-1].ID+1 {
+					t.Fatalf("event ids are not consecutive: %d after %d", events[i].ID, events[i-1].ID)
+				}
+			}
+
+			sub, missed, resumed := w.bus.Subscribe("alice", tt.after(events))
+			defer sub.Close()
+			if resumed != tt.wantResumed || len(missed) != tt.wantMissed {
+				t.Fatalf("Subscribe = %d missed, resumed %v; want %d, %v", len(missed), resumed, tt.wantMissed, tt.wantResumed)
+			}
+			for i, ev := range missed {
+				if want := events[len(events)-len(missed)+i]; ev.ID != want.ID || ev.Key != want.Key {
+					t.Fatalf("missed[%d] = %d, want %d", i, ev.ID, want.ID)
+				}
+			}
+			// Подписка получает новые события, даже если история потеряна
+			created := w.create("alice", "после подписки")
+			if ev := next(t, sub); ev.Task.ID != created.ID || ev.ID != events[3].ID+1 {
+				t.Fatalf("live event %d for %s, want %d for %s", ev.ID, ev.Task.ID, events[3].ID+1, created.ID)
+			}
+		})
+	}
+}
+
+func TestEventBusDropsSlowSubscriber(t *testing.T) {
+	w := newWatchedService(t, service.DefaultEventHistory)
+	slow, _, _ := w.bus.Subscribe("alice", 0)
+	defer slow.Close()
+
+	const total = 200
+	for i := 0; i < total; i++ {
+		w.create("alice", "задача")
+	}
+
+	// Подписчик, который не забирал события, отключён: канал закрыт после буфера
+	var received []service.Event
+	for ev := range slow.Events() {
+		received = append(received, ev)
+	}
+	if len(received) == 0 || len(received) >= total {
+		t.Fatalf("slow subscriber received %d of %d events before being dropped", len(received), total)
+	}
+
+	// Переподключение с последним полученным id возвращает остальное
+	sub, missed, resumed := w.bus.Subscribe("alice", received[len(received)-1].ID)
+	defer sub.Close()
+	if !resumed || len(received)+len(missed) != total {
+		t.Fatalf("resume after drop: %d missed, resumed %v; want %d", len(missed), resumed, total-len(received))
+	}
+
+	// Повторный Close безопасен
+	slow.Close()
+}
+
+func TestEventForSubjectTracksAccess(t *testing.T) {
+	ctx := context.Background()
+	w := newWatchedService(t, service.DefaultEventHistory)
+	bob, _, _ := w.bus.Subscribe("bob", 0)
+	defer bob.Close()
+
+	task := w.create("alice", "задача")
+	noEvent(t, bob)
+
+	steps := []struct {
+		name string
+		do   func() error
+		// want — тип события, которое видит bob; пустой — bob ничего не видит
+		want service.EventType
+		// wantShared — есть ли bob в shares задачи из события
+		wantShared bool
+	}{
+		{"shared with bob", func() error {
+			_, err := w.svc.Share(ctx, "alice", task.ID, "bob", service.PermissionViewer)
+			return err
+		}, service.EventCreated, true},
+		{"updated while shared", func() error {
+			_, err := w.svc.Update(ctx, "alice", task.ID, nil, func(t service.Task) (service.Task, error) {
+				t.Done = true
+				return t, nil
+			})
+			return err
+		}, service.EventUpdated, true},
+		{"permission changed", func() error {
+			_, err := w.svc.Share(ctx, "alice", task.ID, "bob", service.PermissionEditor)
+			return err
+		}, service.EventUpdated, true},
+		// Потерю доступа bob видит как удаление задачи в последнем доступном ему состоянии
+		{"unshared from bob", func() error {
+			_, err := w.svc.Unshare(ctx, "alice", task.ID, "bob")
+			return err
+		}, service.EventDeleted, true},
+		{"updated after unshare", func() error {
+			_, err := w.svc.Update(ctx, "alice", task.ID, nil, func(t service.Task) (service.Task, error) {
+				t.Title = "переименована"
+				return t, nil
+			})
+			return err
+		}, "", false},
+		{"shared with someone else", func() error {
+			_, err := w.svc.Share(ctx, "alice", task.ID, "carol", service.PermissionViewer)
+			return err
+		}, "", false},
+		{"deleted", func() error {
+			return w.svc.Delete(ctx, "alice", task.ID, nil)
+		}, "", false},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		w.drain()
+		if step.want == "" {
+			noEvent(t, bob)
+			continue
+		}
+		ev := next(t, bob)
+		_, shared := ev.Task.Shares["bob"]
+		if ev.Type != step.want || ev.Task.ID != task.ID || shared != step.wantShared {
+			t.Fatalf("%s: bob sees %s (shared %v), want %s (shared %v)", step.name, ev.Type, shared, step.want, step.wantShared)
+		}
+	}
+}
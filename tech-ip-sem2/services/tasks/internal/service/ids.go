@@ -0,0 +1,20 @@
+package service
+
+import "github.com/google/uuid"
+
+// maxIDAttempts — сколько раз Create пробует новый id, если сгенерированный уже занят
+const maxIDAttempts = 3
+
+// IDGenerator выдаёт идентификаторы новых задач
+type IDGenerator func() (string, error)
+
+// UUIDv7IDs — генератор по умолчанию: UUIDv7 (RFC 9562) с префиксом "t_".
+// Идентификаторы уникальны без координации между экземплярами и упорядочены
+// по времени создания с точностью до миллисекунды.
+func UUIDv7IDs() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return "t_" + id.String(), nil
+}
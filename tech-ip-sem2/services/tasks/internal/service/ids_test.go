@@ -0,0 +1,67 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// sequenceIDs выдаёт ids по порядку, а затем повторяет последний; calls считает вызовы
+type sequenceIDs struct {
+	ids   []string
+	calls int
+}
+
+func (s *sequenceIDs) next() (string, error) {
+	id := s.ids[min(s.calls, len(s.ids)-1)]
+	s.calls++
+	return id, nil
+}
+
+func TestCreateRetriesTakenID(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		ids       []string
+		wantID    string
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "free id", ids: []string{"t_2"}, wantID: "t_2", wantCalls: 1},
+		{name: "taken id is retried", ids: []string{"t_1", "t_1", "t_2"}, wantID: "t_2", wantCalls: 3},
+		// Генератор, который раз за разом повторяется, сломан: Create сдаётся после maxIDAttempts
+		{name: "attempts run out", ids: []string{"t_1"}, wantErr: service.ErrTaskExists, wantCalls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := service.NewMemoryTaskRepository()
+			repo.Put(service.Task{ID: "t_1", Owner: "bob", Title: "занято", Version: 1})
+			gen := &sequenceIDs{ids: tt.ids}
+			svc := service.NewTaskService(repo, gen.next, service.NewEventBus(service.DefaultEventHistory))
+
+			created, err := svc.Create(ctx, service.Task{Owner: "alice", Title: "задача"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create err = %v, want %v", err, tt.wantErr)
+			}
+			if created.ID != tt.wantID || gen.calls != tt.wantCalls {
+				t.Fatalf("Create = %q after %d ids, want %q after %d", created.ID, gen.calls, tt.wantID, tt.wantCalls)
+			}
+			// Занятая задача не перезаписана
+			if taken, err := repo.Get(ctx, "t_1"); err != nil || taken.Owner != "bob" {
+				t.Fatalf("existing task = %+v, %v", taken, err)
+			}
+		})
+	}
+}
+
+func TestCreateReportsGeneratorError(t *testing.T) {
+	errEntropy := errors.New("entropy source failed")
+	svc := service.NewTaskService(service.NewMemoryTaskRepository(),
+		func() (string, error) { return "", errEntropy },
+		service.NewEventBus(service.DefaultEventHistory))
+	if _, err := svc.Create(context.Background(), service.Task{Owner: "alice", Title: "задача"}); !errors.Is(err, errEntropy) {
+		t.Fatalf("Create err = %v, want %v", err, errEntropy)
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outboxBatch — сколько событий outbox читается и подтверждается за один раз
+const outboxBatch = 100
+
+// OutboxEvent — событие об изменении задачи, записанное в хранилище в одной
+// транзакции с самим изменением. Key не меняется при повторной отправке события,
+// по нему получатели отбрасывают дубликаты.
+type OutboxEvent struct {
+	Key        string
+	Type       EventType
+	Task       Task
+	Before     *Task
+	OccurredAt time.Time
+}
+
+// EventConsumer — потребитель событий outbox, которому нужна доставка не реже одного раза.
+// ConsumeEvents должна сохранить события надёжно (или обработать их) до возврата:
+// событие удаляется из outbox, только когда его приняли все потребители, а после
+// ошибки передаётся повторно с тем же Key.
+type EventConsumer interface {
+	ConsumeEvents(ctx context.Context, events []Event) error
+}
+
+// event возвращает событие outbox без номера в шине
+func (e OutboxEvent) event() Event {
+	return Event{Key: e.Key, Type: e.Type, Task: e.Task, OccurredAt: e.OccurredAt, before: e.Before}
+}
+
+// pendingEvent — изменение задачи, событие о котором нужно записать в outbox
+type pendingEvent struct {
+	typ    EventType
+	before *Task
+	task   Task
+}
+
+// append записывает событие в outbox через repo — в той же транзакции, что и изменение
+func (e pendingEvent) append(ctx context.Context, repo TaskRepository) error {
+	key, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("generate event key: %w", err)
+	}
+	return repo.AppendEvent(ctx, OutboxEvent{
+		Key:        "ev_" + key.String(),
+		Type:       e.typ,
+		Task:       e.task,
+		Before:     e.before,
+		OccurredAt: time.Now(),
+	})
+}
+
+// commit выполняет изменение fn и записывает его событие в outbox одной транзакцией:
+// событие появляется, только если изменение сохранено
+func (s *TaskService) commit(ctx context.Context, fn func(tx TaskRepository) (pendingEvent, error)) error {
+	err := s.repo.InTx(ctx, func(tx TaskRepository) error {
+		ev, err := fn(tx)
+		if err != nil {
+			return err
+		}
+		return ev.append(ctx, tx)
+	})
+	if err == nil {
+		s.outboxReady()
+	}
+	return err
+}
+
+// outboxReady будит RunOutbox после фиксации изменения, не дожидаясь таймера
+func (s *TaskService) outboxReady() {
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// DrainOutbox передаёт все накопившиеся события outbox по порядку в EventBus и
+// потребителям и удаляет их из хранилища. Событие удаляется только после того, как
+// его приняли все потребители, поэтому при сбое оно будет передано ещё раз с тем же Key.
+// Возвращает число переданных событий.
+//
+// EventBus живёт в памяти процесса: если несколько экземпляров сервиса работают
+// с одной базой, событие попадает в шину только того экземпляра, который разобрал
+// outbox, и подписчики SSE и gRPC Watch других экземпляров его не увидят.
+func (s *TaskService) DrainOutbox(ctx context.Context) (int, error) {
+	s.drainMu.Lock()
+	defer s.drainMu.Unlock()
+
+	n := 0
+	for {
+		events, err := s.repo.PendingEvents(ctx, outboxBatch)
+		if err != nil {
+			return n, fmt.Errorf("read outbox: %w", err)
+		}
+		if len(events) == 0 {
+			return n, nil
+		}
+		keys := make([]string, len(events))
+		consumed := make([]Event, len(events))
+		for i, ev := range events {
+			s.events.publish(ev)
+			keys[i] = ev.Key
+			consumed[i] = ev.event()
+		}
+		for _, c := range s.consumers {
+			if err := c.ConsumeEvents(ctx, consumed); err != nil {
+				return n, fmt.Errorf("consume outbox: %w", err)
+			}
+		}
+		if err := s.repo.AckEvents(ctx, keys); err != nil {
+			return n, fmt.Errorf("ack outbox: %w", err)
+		}
+		n += len(events)
+		if len(events) < outboxBatch {
+			return n, nil
+		}
+	}
+}
+
+// RunOutbox разбирает outbox сразу после каждого изменения задач и раз в interval,
+// чтобы подобрать события, оставшиеся после сбоя или перезапуска, пока не отменён ctx
+func (s *TaskService) RunOutbox(ctx context.Context, interval time.Duration, onDrain func(n int, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.DrainOutbox(ctx)
+		if onDrain != nil && (n > 0 || err != nil) {
+			onDrain(n, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.outboxWake:
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// recordingConsumer запоминает ключи принятых событий; пока fail задан, отказывает
+type recordingConsumer struct {
+	fail error
+	keys []string
+}
+
+func (c *recordingConsumer) ConsumeEvents(ctx context.Context, events []service.Event) error {
+	if c.fail != nil {
+		return c.fail
+	}
+	for _, ev := range events {
+		c.keys = append(c.keys, ev.Key)
+	}
+	return nil
+}
+
+func TestDrainOutboxKeepsEventsUntilConsumed(t *testing.T) {
+	ctx := context.Background()
+	failing := &recordingConsumer{fail: errors.New("queue is unavailable")}
+	healthy := &recordingConsumer{}
+	bus := service.NewEventBus(service.DefaultEventHistory)
+	svc := service.NewTaskService(service.NewMemoryTaskRepository(), service.UUIDv7IDs, bus, healthy, failing)
+	sub, _, _ := bus.Subscribe("alice", 0)
+	defer sub.Close()
+
+	if _, err := svc.Create(ctx, service.Task{Owner: "alice", Title: "задача"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Потребитель не принял событие — оно остаётся в outbox
+	if _, err := svc.DrainOutbox(ctx); !errors.Is(err, failing.fail) {
+		t.Fatalf("DrainOutbox err = %v, want consumer error", err)
+	}
+	failing.fail = nil
+	n, err := svc.DrainOutbox(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("DrainOutbox after recovery = %d, %v; want 1 event", n, err)
+	}
+	if n, err := svc.DrainOutbox(ctx); err != nil || n != 0 {
+		t.Fatalf("DrainOutbox after ack = %d, %v; want 0 events", n, err)
+	}
+
+	// Повтор идёт с тем же Key, чтобы потребители могли отбросить дубликат
+	if len(failing.keys) != 1 || len(healthy.keys) != 2 || healthy.keys[0] != healthy.keys[1] || failing.keys[0] != healthy.keys[0] {
+		t.Fatalf("consumed keys: healthy %v, failing %v", healthy.keys, failing.keys)
+	}
+	// Подписчики шины получают событие один раз
+	if ev := <-sub.Events(); ev.Key != healthy.keys[0] {
+		t.Fatalf("bus event key = %q, want %q", ev.Key, healthy.keys[0])
+	}
+	select {
+	case ev := <-sub.Events():
+		t.Fatalf("bus delivered event %q twice", ev.Key)
+	default:
+	}
+}
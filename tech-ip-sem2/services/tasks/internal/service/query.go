@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Поля, по которым можно сортировать список задач
+const (
+	SortCreatedAt = "created_at"
+	SortDueDate   = "due_date"
+	SortTitle     = "title"
+)
+
+var (
+	ErrInvalidQuery  = errors.New("invalid query")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// ListQuery — параметры выборки списка задач. Нулевое значение означает
+// первую страницу размером DefaultPageSize, отсортированную по created_at.
+type ListQuery struct {
+	Done      *bool
+	DueBefore time.Time
+	DueAfter  time.Time
+	// Q — подстрока для поиска в заголовке без учёта регистра
+	Q string
+	// Tags — задачи со всеми этими тегами или, если AnyTag, хотя бы с одним из них
+	Tags   []string
+	AnyTag bool
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// TaskPage — страница списка задач; NextCursor пуст на последней странице
+type TaskPage struct {
+	Tasks      []Task
+	NextCursor string
+}
+
+// cursor — позиция последней выданной задачи. Вместе с ней хранится сортировка,
+// чтобы курсор нельзя было применить к выборке с другим порядком.
+type cursor struct {
+	Sort      string    `json:"s"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"c"`
+	DueDate   DueDate   `json:"d"`
+	Title     string    `json:"t,omitempty"`
+}
+
+func (q *ListQuery) normalize() error {
+	if q.Sort == "" {
+		q.Sort = SortCreatedAt
+	}
+	switch q.Sort {
+	case SortCreatedAt, SortDueDate, SortTitle:
+	default:
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, q.Sort)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit < 0 || q.Limit > MaxPageSize {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageSize)
+	}
+	tags := make([]string, 0, len(q.Tags))
+	for _, t := range q.Tags {
+		tag, err := NormalizeTag(t)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	q.Tags = tags
+	return nil
+}
+
+func (q ListQuery) match(t Task) bool {
+	if q.Done != nil && t.Done != *q.Done {
+		return false
+	}
+	if q.Q != "" && !strings.Contains(strings.ToLower(t.Title), strings.ToLower(q.Q)) {
+		return false
+	}
+	if len(q.Tags) > 0 {
+		has := func(tag string) bool { return slices.Contains(t.Tags, tag) }
+		if q.AnyTag && !slices.ContainsFunc(q.Tags, has) {
+			return false
+		}
+		if !q.AnyTag && !allOf(q.Tags, has) {
+			return false
+		}
+	}
+	if !q.DueBefore.IsZero() || !q.DueAfter.IsZero() {
+		// Задачи без срока не попадают в выборку по сроку
+		if t.DueDate.IsZero() {
+			return false
+		}
+		if !q.DueBefore.IsZero() && !t.DueDate.Time.Before(q.DueBefore) {
+			return false
+		}
+		if !q.DueAfter.IsZero() && !t.DueDate.Time.After(q.DueAfter) {
+			return false
+		}
+	}
+	return true
+}
+
+func allOf(tags []string, has func(string) bool) bool {
+	for _, tag := range tags {
+		if !has(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// less задаёт порядок задач для сортировки field. При равенстве ключей
+// порядок определяет id, поэтому он стабилен между запросами.
+func less(field string, a, b Task) bool {
+	switch field {
+	case SortTitle:
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+	case SortDueDate:
+		// Задачи без срока идут в конце
+		switch {
+		case !a.DueDate.IsZero() && !b.DueDate.IsZero() && !a.DueDate.Time.Equal(b.DueDate.Time):
+			return a.DueDate.Before(b.DueDate)
+		case !a.DueDate.IsZero() && b.DueDate.IsZero():
+			return true
+		case a.DueDate.IsZero() && !b.DueDate.IsZero():
+			return false
+		}
+	default:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+	return a.ID < b.ID
+}
+
+func encodeCursor(field string, t Task) string {
+	c := cursor{Sort: field, ID: t.ID}
+	switch field {
+	case SortTitle:
+		c.Title = t.Title
+	case SortDueDate:
+		c.DueDate = t.DueDate
+	default:
+		c.CreatedAt = t.CreatedAt
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor восстанавливает из курсора задачу-ориентир с полями, нужными для сравнения
+func decodeCursor(field, raw string) (Task, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return Task{}, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return Task{}, ErrInvalidCursor
+	}
+	if c.Sort != field {
+		return Task{}, fmt.Errorf("%w: cursor was issued for sort=%s", ErrInvalidCursor, c.Sort)
+	}
+	return Task{ID: c.ID, CreatedAt: c.CreatedAt, DueDate: c.DueDate, Title: c.Title}, nil
+}
+
+// PageQuery — проверенный ListQuery с разобранным курсором; его получает TaskPager
+type PageQuery struct {
+	ListQuery
+	// After — задача-ориентир из курсора: страница начинается строго после неё
+	// в порядке Sort; nil — с начала списка
+	After *Task
+}
+
+// TaskPager — хранилище, которое само выбирает страницу списка задач: фильтры,
+// сортировка, курсор и лимит выполняются в базе. Хранилища без него отдают
+// ListVisible, и страница собирается в памяти.
+type TaskPager interface {
+	// ListPage возвращает до q.Limit задач, видимых subject и подходящих под фильтры q,
+	// в порядке q.Sort (при равенстве — по id), начиная после q.After. Задачи без срока
+	// при сортировке по due_date идут в конце.
+	ListPage(ctx context.Context, subject string, q PageQuery) ([]Task, error)
+}
+
+// page проверяет запрос и разбирает курсор
+func (q ListQuery) page() (PageQuery, error) {
+	if err := q.normalize(); err != nil {
+		return PageQuery{}, err
+	}
+	pq := PageQuery{ListQuery: q}
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Sort, q.Cursor)
+		if err != nil {
+			return PageQuery{}, err
+		}
+		pq.After = &c
+	}
+	return pq, nil
+}
+
+// paginate выбирает из tasks то же, что TaskPager.ListPage: фильтрует, сортирует
+// и оставляет первые q.Limit задач после q.After
+func paginate(tasks []Task, q PageQuery) []Task {
+	matched := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if !q.match(t) {
+			continue
+		}
+		if q.After != nil && !less(q.Sort, *q.After, t) {
+			continue
+		}
+		matched = append(matched, t)
+	}
+	sort.Slice(matched, func(i, j int) bool { return less(q.Sort, matched[i], matched[j]) })
+	if len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	return matched
+}
+
+// newPage собирает страницу из задач, выбранных с запасом в одну: лишняя задача
+// означает, что за страницей есть продолжение
+func newPage(q PageQuery, tasks []Task) TaskPage {
+	page := TaskPage{Tasks: tasks}
+	if len(tasks) > q.Limit {
+		page.Tasks = tasks[:q.Limit]
+		page.NextCursor = encodeCursor(q.Sort, page.Tasks[q.Limit-1])
+	}
+	return page
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// TaskRepository — хранилище задач. Проверки доступа выполняет TaskService,
+// репозиторий только сохраняет и находит задачи.
+type TaskRepository interface {
+	// Create сохраняет новую задачу; если задача с таким id уже есть, возвращает ErrTaskExists
+	Create(ctx context.Context, task Task) error
+	// Get возвращает ErrTaskNotFound, если задачи нет
+	Get(ctx context.Context, id string) (Task, error)
+	// ListVisible возвращает задачи, которые принадлежат subject или которыми с ним поделились
+	ListVisible(ctx context.Context, subject string) ([]Task, error)
+	// Update целиком заменяет сохранённую задачу, включая список доступа.
+	// Замена выполняется, только если сохранённая версия равна task.Version-1,
+	// иначе возвращается ErrVersionConflict.
+	Update(ctx context.Context, task Task) error
+	Delete(ctx context.Context, id string) error
+	// InTx выполняет fn атомарно: изменения, сделанные через переданный fn
+	// репозиторий, сохраняются все вместе, если fn не вернула ошибку, иначе отбрасываются
+	InTx(ctx context.Context, fn func(tx TaskRepository) error) error
+
+	// AppendEvent добавляет событие в outbox; внутри InTx событие сохраняется
+	// или отбрасывается вместе с остальными изменениями транзакции
+	AppendEvent(ctx context.Context, ev OutboxEvent) error
+	// PendingEvents возвращает до limit первых событий outbox в порядке добавления
+	PendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error)
+	// AckEvents удаляет из outbox опубликованные события; неизвестные ключи пропускаются
+	AckEvents(ctx context.Context, keys []string) error
+}
+
+// MemoryTaskRepository хранит задачи в памяти процесса
+type MemoryTaskRepository struct {
+	mu     sync.RWMutex
+	tasks  map[string]Task
+	outbox []OutboxEvent
+}
+
+func NewMemoryTaskRepository() *MemoryTaskRepository {
+	return &MemoryTaskRepository{
+		tasks: make(map[string]Task),
+	}
+}
+
+func (r *MemoryTaskRepository) Create(ctx context.Context, task Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tasks[task.ID]; ok {
+		return ErrTaskExists
+	}
+	r.tasks[task.ID] = task
+	return nil
+}
+
+func (r *MemoryTaskRepository) Get(ctx context.Context, id string) (Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	task, ok := r.tasks[id]
+	if !ok {
+		return Task{}, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (r *MemoryTaskRepository) ListVisible(ctx context.Context, subject string) ([]Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tasks := make([]Task, 0)
+	for _, t := range r.tasks {
+		if t.CanView(subject) {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+func (r *MemoryTaskRepository) Update(ctx context.Context, task Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.tasks[task.ID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+	if stored.Version != task.Version-1 {
+		return ErrVersionConflict
+	}
+	r.tasks[task.ID] = task
+	return nil
+}
+
+func (r *MemoryTaskRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tasks[id]; !ok {
+		return ErrTaskNotFound
+	}
+	delete(r.tasks, id)
+	return nil
+}
+
+// InTx применяет fn к копии задач и подменяет ею текущее состояние, если fn
+// завершилась успешно. Остальные вызовы на это время блокируются.
+func (r *MemoryTaskRepository) InTx(ctx context.Context, fn func(tx TaskRepository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	draft := r.cloneLocked()
+	if err := fn(draft); err != nil {
+		return err
+	}
+	r.tasks = draft.tasks
+	r.outbox = draft.outbox
+	return nil
+}
+
+// AppendEvent не добавляет событие повторно, если его Key уже есть в outbox:
+// так журнал можно проиграть поверх снимка, уже содержащего это событие
+func (r *MemoryTaskRepository) AppendEvent(ctx context.Context, ev OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if slices.ContainsFunc(r.outbox, func(e OutboxEvent) bool { return e.Key == ev.Key }) {
+		return nil
+	}
+	r.outbox = append(r.outbox, ev)
+	return nil
+}
+
+func (r *MemoryTaskRepository) PendingEvents(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.outbox[:min(limit, len(r.outbox))]), nil
+}
+
+func (r *MemoryTaskRepository) AckEvents(ctx context.Context, keys []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outbox = slices.DeleteFunc(r.outbox, func(ev OutboxEvent) bool {
+		return slices.Contains(keys, ev.Key)
+	})
+	return nil
+}
+
+func (r *MemoryTaskRepository) cloneLocked() *MemoryTaskRepository {
+	return &MemoryTaskRepository{tasks: maps.Clone(r.tasks), outbox: slices.Clone(r.outbox)}
+}
+
+// Put сохраняет задачу без проверок существования и версии; нужен при восстановлении из журнала
+func (r *MemoryTaskRepository) Put(task Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[task.ID] = task
+}
+
+// All возвращает все сохранённые задачи без учёта прав доступа
+func (r *MemoryTaskRepository) All() []Task {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tasks := make([]Task, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// Outbox возвращает все события outbox; нужен для снимка журнала
+func (r *MemoryTaskRepository) Outbox() []OutboxEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.outbox)
+}
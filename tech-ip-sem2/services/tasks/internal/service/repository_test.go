@@ -0,0 +1,14 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/storagetest"
+)
+
+func TestMemoryTaskRepository(t *testing.T) {
+	storagetest.RunTaskRepositoryTests(t, func(*testing.T) service.TaskRepository {
+		return service.NewMemoryTaskRepository()
+	})
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxTags — наибольшее число тегов у задачи
+	MaxTags      = 20
+	maxTagLength = 32
+)
+
+var (
+	ErrInvalidTag  = errors.New("invalid tag")
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+// TagCount — тег и число задач пользователя с этим тегом
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// NormalizeTag приводит имя тега к каноническому виду: без пробелов по краям и в нижнем
+// регистре. Допустимы буквы, цифры, '-', '_' и '.', не длиннее 32 символов;
+// первый символ — буква или цифра.
+func NormalizeTag(name string) (string, error) {
+	tag := strings.ToLower(strings.TrimSpace(name))
+	if tag == "" {
+		return "", fmt.Errorf("%w: tag must not be empty", ErrInvalidTag)
+	}
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, name, maxTagLength)
+	}
+	for i, r := range tag {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+		case i > 0 && (r == '-' || r == '_' || r == '.'):
+		default:
+			return "", fmt.Errorf("%w: %q may contain only letters, digits, '-', '_' and '.' and must start with a letter or digit", ErrInvalidTag, name)
+		}
+	}
+	return tag, nil
+}
+
+// normalizeTags нормализует теги задачи, убирает повторы и сортирует их
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, t := range tags {
+		tag, err := NormalizeTag(t)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("%w: a task may have at most %d tags", ErrInvalidTag, MaxTags)
+	}
+	return normalized, nil
+}
+
+// Tags возвращает теги собственных задач subject с числом задач для каждого, по алфавиту
+func (s *TaskService) Tags(ctx context.Context, subject string) ([]TagCount, error) {
+	tasks, err := ownTasks(ctx, s.repo, subject)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, t := range tasks {
+		for _, tag := range t.Tags {
+			counts[tag]++
+		}
+	}
+	result := make([]TagCount, 0, len(counts))
+	for name, n := range counts {
+		result = append(result, TagCount{Name: name, Count: n})
+	}
+	slices.SortFunc(result, func(a, b TagCount) int { return strings.Compare(a.Name, b.Name) })
+	return result, nil
+}
+
+// RenameTag переименовывает тег from в to во всех собственных задачах subject.
+// Если тег to уже используется, возвращается ErrTagExists — для объединения есть MergeTags.
+// Возвращает число изменённых задач.
+func (s *TaskService) RenameTag(ctx context.Context, subject, from, to string) (int, error) {
+	return s.retag(ctx, subject, []string{from}, to, true)
+}
+
+// MergeTags заменяет теги sources тегом target во всех собственных задачах subject;
+// target может уже использоваться. Возвращает число изменённых задач.
+func (s *TaskService) MergeTags(ctx context.Context, subject string, sources []string, target string) (int, error) {
+	if len(sources) == 0 {
+		return 0, fmt.Errorf("%w: at least one source tag is required", ErrInvalidTag)
+	}
+	return s.retag(ctx, subject, sources, target, false)
+}
+
+// retag заменяет теги sources тегом target в одной транзакции: меняются
+// все задачи или ни одной. Каждая изменённая задача получает новую версию и событие.
+func (s *TaskService) retag(ctx context.Context, subject string, sources []string, target string, rename bool) (int, error) {
+	target, err := NormalizeTag(target)
+	if err != nil {
+		return 0, err
+	}
+	from := make([]string, 0, len(sources))
+	for _, src := range sources {
+		tag, err := NormalizeTag(src)
+		if err != nil {
+			return 0, err
+		}
+		if tag != target {
+			from = append(from, tag)
+		}
+	}
+	if len(from) == 0 {
+		return 0, fmt.Errorf("%w: source and target tags are the same", ErrInvalidTag)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := 0
+	err = s.repo.InTx(ctx, func(tx TaskRepository) error {
+		tasks, err := ownTasks(ctx, tx, subject)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, t := range tasks {
+			if slices.ContainsFunc(t.Tags, func(tag string) bool { return slices.Contains(from, tag) }) {
+				found = true
+			}
+			if rename && slices.Contains(t.Tags, target) {
+				return fmt.Errorf("%w: %q", ErrTagExists, target)
+			}
+		}
+		if !found {
+			return ErrTagNotFound
+		}
+
+		for _, before := range tasks {
+			tags := make([]string, 0, len(before.Tags))
+			for _, tag := range before.Tags {
+				if slices.Contains(from, tag) {
+					tag = target
+				}
+				tags = append(tags, tag)
+			}
+			tags, _ = normalizeTags(tags)
+			if slices.Equal(tags, before.Tags) {
+				continue
+			}
+			task := before
+			task.Tags = tags
+			task.Version++
+			task.UpdatedAt = time.Now()
+			if err := tx.Update(ctx, task); err != nil {
+				return err
+			}
+			if err := (pendingEvent{typ: EventUpdated, before: &before, task: task}).append(ctx, tx); err != nil {
+				return err
+			}
+			changed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.outboxReady()
+	return changed, nil
+}
+
+// ownTasks возвращает задачи, владельцем которых является subject
+func ownTasks(ctx context.Context, repo TaskRepository, subject string) ([]Task, error) {
+	tasks, err := repo.ListVisible(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(tasks, func(t Task) bool { return t.Owner != subject }), nil
+}
@@ -0,0 +1,211 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"lower case", "work", "work", false},
+		{"upper case", "Work", "work", false},
+		{"cyrillic upper case", "Дом", "дом", false},
+		{"surrounding whitespace", "  urgent\t", "urgent", false},
+		{"inner punctuation", "v1.2_rc-3", "v1.2_rc-3", false},
+		{"starts with digit", "2026", "2026", false},
+		{"max length", strings.Repeat("a", 32), strings.Repeat("a", 32), false},
+		// Длина считается в символах, а не в байтах
+		{"max length in runes", strings.Repeat("я", 32), strings.Repeat("я", 32), false},
+		{"too long", strings.Repeat("a", 33), "", true},
+		{"empty", "", "", true},
+		{"only whitespace", "   ", "", true},
+		{"inner space", "bad tag", "", true},
+		{"starts with dash", "-work", "", true},
+		{"starts with dot", ".work", "", true},
+		{"slash", "home/work", "", true},
+		{"hash", "#work", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.NormalizeTag(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, service.ErrInvalidTag) {
+					t.Fatalf("NormalizeTag(%q) = %q, %v; want ErrInvalidTag", tt.in, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("NormalizeTag(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// tagged создаёт задачу owner с тегами tags
+func tagged(t *testing.T, svc *service.TaskService, owner string, tags ...string) service.Task {
+	t.Helper()
+	task, err := svc.Create(context.Background(), service.Task{Owner: owner, Title: "задача", Tags: tags})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return task
+}
+
+// reload возвращает сохранённую задачу
+func reload(t *testing.T, svc *service.TaskService, task service.Task) service.Task {
+	t.Helper()
+	got, err := svc.Get(context.Background(), task.Owner, task.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return got
+}
+
+func TestTagsCountsOwnTasksOnly(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewTaskService(service.NewMemoryTaskRepository(), service.UUIDv7IDs, service.NewEventBus(service.DefaultEventHistory))
+	shared := tagged(t, svc, "alice", "work", "home")
+	tagged(t, svc, "alice", "work")
+	tagged(t, svc, "alice")
+	tagged(t, svc, "bob", "work", "bob-only")
+	if _, err := svc.Share(ctx, "alice", shared.ID, "bob", service.PermissionEditor); err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+
+	tests := []struct {
+		subject string
+		want    []service.TagCount
+	}{
+		{"alice", []service.TagCount{{Name: "home", Count: 1}, {Name: "work", Count: 2}}},
+		// Общая задача alice не входит в теги bob
+		{"bob", []service.TagCount{{Name: "bob-only", Count: 1}, {Name: "work", Count: 1}}},
+		{"carol", []service.TagCount{}},
+	}
+	for _, tt := range tests {
+		got, err := svc.Tags(ctx, tt.subject)
+		if err != nil {
+			t.Fatalf("Tags(%s): %v", tt.subject, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Tags(%s) = %v, want %v", tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewTaskService(service.NewMemoryTaskRepository(), service.UUIDv7IDs, service.NewEventBus(service.DefaultEventHistory))
+	first := tagged(t, svc, "alice", "work", "urgent")
+	second := tagged(t, svc, "alice", "work")
+	other := tagged(t, svc, "alice", "home")
+	foreign := tagged(t, svc, "bob", "work", "bob-tag")
+	if _, err := svc.Share(ctx, "bob", foreign.ID, "alice", service.PermissionEditor); err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+	foreign = reload(t, svc, foreign)
+
+	errTests := []struct {
+		name     string
+		from, to string
+		wantErr  error
+	}{
+		{"into existing tag", "work", "urgent", service.ErrTagExists},
+		{"into existing tag in other case", "work", " URGENT ", service.ErrTagExists},
+		{"missing tag", "missing", "job", service.ErrTagNotFound},
+		// Теги общих задач bob alice не переименовывает
+		{"tag of shared task only", "bob-tag", "job", service.ErrTagNotFound},
+		{"same name", "Work", "work", service.ErrInvalidTag},
+		{"invalid target", "work", "bad tag", service.ErrInvalidTag},
+	}
+	for _, tt := range errTests {
+		if n, err := svc.RenameTag(ctx, "alice", tt.from, tt.to); !errors.Is(err, tt.wantErr) || n != 0 {
+			t.Errorf("%s: RenameTag = %d, %v; want %v", tt.name, n, err, tt.wantErr)
+		}
+	}
+	// Отказ ничего не меняет
+	if got := reload(t, svc, first); got.Version != first.Version || !slices.Equal(got.Tags, first.Tags) {
+		t.Fatalf("task after failed rename: version %d, tags %v", got.Version, got.Tags)
+	}
+
+	n, err := svc.RenameTag(ctx, "alice", "WORK", "Job")
+	if err != nil || n != 2 {
+		t.Fatalf("RenameTag = %d, %v; want 2 tasks", n, err)
+	}
+	for _, tt := range []struct {
+		before service.Task
+		want   []string
+	}{
+		{first, []string{"job", "urgent"}},
+		{second, []string{"job"}},
+		{other, []string{"home"}},
+		{foreign, []string{"bob-tag", "work"}},
+	} {
+		got := reload(t, svc, tt.before)
+		if !slices.Equal(got.Tags, tt.want) {
+			t.Errorf("task %s tags = %v, want %v", tt.before.ID, got.Tags, tt.want)
+		}
+		// Версия растёт только у изменённых задач
+		if changed := !slices.Equal(tt.before.Tags, tt.want); (got.Version != tt.before.Version) != changed {
+			t.Errorf("task %s version %d -> %d", tt.before.ID, tt.before.Version, got.Version)
+		}
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewTaskService(service.NewMemoryTaskRepository(), service.UUIDv7IDs, service.NewEventBus(service.DefaultEventHistory))
+	both := tagged(t, svc, "alice", "todo", "backlog")
+	withTarget := tagged(t, svc, "alice", "todo", "work")
+	source := tagged(t, svc, "alice", "later")
+	untouched := tagged(t, svc, "alice", "home")
+	foreign := tagged(t, svc, "bob", "backlog")
+
+	if _, err := svc.MergeTags(ctx, "alice", nil, "todo"); !errors.Is(err, service.ErrInvalidTag) {
+		t.Fatalf("MergeTags without sources: %v, want ErrInvalidTag", err)
+	}
+	if _, err := svc.MergeTags(ctx, "alice", []string{"missing"}, "todo"); !errors.Is(err, service.ErrTagNotFound) {
+		t.Fatalf("MergeTags of missing tag: %v, want ErrTagNotFound", err)
+	}
+
+	// Цель среди источников не считается; задача с целью без источников не меняется
+	n, err := svc.MergeTags(ctx, "alice", []string{"Backlog", "later", "todo", "later"}, "TODO")
+	if err != nil || n != 2 {
+		t.Fatalf("MergeTags = %d, %v; want 2 tasks", n, err)
+	}
+	for _, tt := range []struct {
+		before  service.Task
+		want    []string
+		changed bool
+	}{
+		// Два источника в одной задаче дают один тег
+		{both, []string{"todo"}, true},
+		{withTarget, []string{"todo", "work"}, false},
+		{source, []string{"todo"}, true},
+		{untouched, []string{"home"}, false},
+		{foreign, []string{"backlog"}, false},
+	} {
+		got := reload(t, svc, tt.before)
+		if !slices.Equal(got.Tags, tt.want) || (got.Version != tt.before.Version) != tt.changed {
+			t.Errorf("task %s: tags %v, version %d -> %d; want %v, changed %v", tt.before.ID, got.Tags, tt.before.Version, got.Version, tt.want, tt.changed)
+		}
+	}
+
+	tags, err := svc.Tags(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Tags: %v", err)
+	}
+	want := []service.TagCount{{Name: "home", Count: 1}, {Name: "todo", Count: 3}, {Name: "work", Count: 1}}
+	if !slices.Equal(tags, want) {
+		t.Fatalf("Tags after merge = %v, want %v", tags, want)
+	}
+}
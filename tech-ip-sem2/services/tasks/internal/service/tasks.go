@@ -1,91 +1,322 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 )
 
+var (
+	ErrTitleRequired      = errors.New("title is required")
+	ErrTaskExists         = errors.New("task already exists")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrVersionConflict возвращает репозиторий, если задачу успели изменить
+	// с момента чтения (например, другой экземпляр сервиса)
+	ErrVersionConflict = errors.New("task was modified concurrently")
+)
+
+// IfMatch — допустимые версии задачи для условного изменения (If-Match).
+// nil означает изменение без условия, пустой срез не допускает ни одной версии.
+type IfMatch []int64
+
+func (m IfMatch) Allows(version int64) bool {
+	return m == nil || slices.Contains(m, version)
+}
+
 type Task struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	DueDate     string    `json:"due_date,omitempty"`
-	Done        bool      `json:"done"`
-	CreatedAt   time.Time `json:"-"`
-	UpdatedAt   time.Time `json:"-"`
+	ID          string                `json:"id"`
+	Owner       string                `json:"owner"`
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	DueDate     DueDate               `json:"due_date"`
+	Done        bool                  `json:"done"`
+	Shares      map[string]Permission `json:"shares,omitempty"`
+	// Tags хранятся нормализованными, без повторов и по алфавиту
+	Tags []string `json:"tags,omitempty"`
+	// Version увеличивается при каждом изменении задачи
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// TaskService применяет правила доступа к задачам поверх TaskRepository.
+// События об изменениях записываются в outbox репозитория вместе с изменением
+// и попадают в EventBus и к потребителям через RunOutbox.
 type TaskService struct {
-	mu    sync.RWMutex
-	tasks map[string]Task
+	// mu сериализует изменения: проверка доступа и запись выполняются как одно действие
+	mu     sync.Mutex
+	repo   TaskRepository
+	newID  IDGenerator
+	events *EventBus
+	// consumers получают события outbox до их удаления из хранилища
+	consumers []EventConsumer
+	// drainMu не даёт двум DrainOutbox опубликовать одно событие дважды
+	drainMu    sync.Mutex
+	outboxWake chan struct{}
 }
 
-func NewTaskService() *TaskService {
+func NewTaskService(repo TaskRepository, newID IDGenerator, events *EventBus, consumers ...EventConsumer) *TaskService {
 	return &TaskService{
-		tasks: make(map[string]Task),
+		repo:       repo,
+		newID:      newID,
+		events:     events,
+		consumers:  consumers,
+		outboxWake: make(chan struct{}, 1),
 	}
 }
 
-func generateID() string {
-	return fmt.Sprintf("t_%d", time.Now().UnixNano())
-}
-
-func (s *TaskService) Create(task Task) Task {
+func (s *TaskService) Create(ctx context.Context, task Task) (Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	task.ID = generateID()
+	var created Task
+	err := s.commit(ctx, func(tx TaskRepository) (pendingEvent, error) {
+		var err error
+		created, err = s.create(ctx, tx, task)
+		return pendingEvent{typ: EventCreated, task: created}, err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return created, nil
+}
+
+func (s *TaskService) create(ctx context.Context, repo TaskRepository, task Task) (Task, error) {
+	if task.Title == "" {
+		return Task{}, ErrTitleRequired
+	}
+	tags, err := normalizeTags(task.Tags)
+	if err != nil {
+		return Task{}, err
+	}
+	task.Tags = tags
+	task.Version = 1
 	task.CreatedAt = time.Now()
-	task.UpdatedAt = time.Now()
-	s.tasks[task.ID] = task
-	return task
+	task.UpdatedAt = task.CreatedAt
+
+	// Репозиторий не перезаписывает существующую задачу; при совпадении id
+	// берём следующий, но не бесконечно — повторы говорят о сломанном генераторе
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		id, err := s.newID()
+		if err != nil {
+			return Task{}, fmt.Errorf("generate task id: %w", err)
+		}
+		task.ID = id
+		err = repo.Create(ctx, task)
+		if errors.Is(err, ErrTaskExists) {
+			continue
+		}
+		if err != nil {
+			return Task{}, err
+		}
+		return task, nil
+	}
+	return Task{}, ErrTaskExists
+}
+
+// Watch подписывает subject на изменения видимых ему задач после события after;
+// подробности — в EventBus.Subscribe
+func (s *TaskService) Watch(subject string, after uint64) (*Subscription, []Event, bool) {
+	return s.events.Subscribe(subject, after)
 }
 
-func (s *TaskService) List() []Task {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	tasks := make([]Task, 0, len(s.tasks))
-	for _, t := range s.tasks {
-		tasks = append(tasks, t)
+// List возвращает страницу задач, доступных subject: собственных и тех, которыми
+// с ним поделились, — с учётом фильтров и сортировки из q. Если хранилище реализует
+// TaskPager, страница выбирается в нём, иначе — в памяти из ListVisible.
+func (s *TaskService) List(ctx context.Context, subject string, q ListQuery) (TaskPage, error) {
+	pq, err := q.page()
+	if err != nil {
+		return TaskPage{}, err
+	}
+	// Задача сверх лимита показывает, есть ли следующая страница
+	fetch := pq
+	fetch.Limit++
+
+	var tasks []Task
+	if pager, ok := s.repo.(TaskPager); ok {
+		tasks, err = pager.ListPage(ctx, subject, fetch)
+	} else {
+		tasks, err = s.repo.ListVisible(ctx, subject)
+		tasks = paginate(tasks, fetch)
 	}
-	return tasks
+	if err != nil {
+		return TaskPage{}, err
+	}
+	return newPage(pq, tasks), nil
 }
 
-func (s *TaskService) Get(id string) (Task, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	task, ok := s.tasks[id]
-	return task, ok
+// Get возвращает задачу, если subject может её видеть или запрос сделан администратором;
+// недоступная задача неотличима от несуществующей
+func (s *TaskService) Get(ctx context.Context, subject, id string) (Task, error) {
+	task, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Task{}, err
+	}
+	if !task.CanView(subject) && !isAdmin(ctx) {
+		return Task{}, ErrTaskNotFound
+	}
+	return task, nil
 }
 
-func (s *TaskService) Update(id string, updated Task) (Task, bool) {
+// Update применяет к задаче изменение change. change получает текущее состояние
+// задачи и возвращает новое; проверка прав, вызов change и запись выполняются
+// под одной блокировкой. id, владелец, список доступа и время создания
+// через Update не меняются. Если версия задачи не входит в ifMatch,
+// возвращается ErrPreconditionFailed.
+func (s *TaskService) Update(ctx context.Context, subject, id string, ifMatch IfMatch, change func(Task) (Task, error)) (Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	task, ok := s.tasks[id]
-	if !ok {
-		return Task{}, false
+	var updated Task
+	err := s.commit(ctx, func(tx TaskRepository) (pendingEvent, error) {
+		var before Task
+		var err error
+		before, updated, err = s.update(ctx, tx, subject, id, ifMatch, change)
+		return pendingEvent{typ: EventUpdated, before: &before, task: updated}, err
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return updated, nil
+}
+
+// update возвращает задачу до и после изменения
+func (s *TaskService) update(ctx context.Context, repo TaskRepository, subject, id string, ifMatch IfMatch, change func(Task) (Task, error)) (Task, Task, error) {
+	task, err := access(ctx, repo, subject, id, Task.CanEdit)
+	if err != nil {
+		return Task{}, Task{}, err
 	}
-	if updated.Title != "" {
-		task.Title = updated.Title
+	if !ifMatch.Allows(task.Version) {
+		return Task{}, Task{}, ErrPreconditionFailed
 	}
-	if updated.Description != "" {
-		task.Description = updated.Description
+	updated, err := change(task)
+	if err != nil {
+		return Task{}, Task{}, err
 	}
-	if updated.DueDate != "" {
-		task.DueDate = updated.DueDate
+	if updated.Title == "" {
+		return Task{}, Task{}, ErrTitleRequired
 	}
-	task.Done = updated.Done
-	task.UpdatedAt = time.Now()
-	s.tasks[id] = task
-	return task, true
+	if updated.Tags, err = normalizeTags(updated.Tags); err != nil {
+		return Task{}, Task{}, err
+	}
+	updated.ID = task.ID
+	updated.Owner = task.Owner
+	updated.Shares = task.Shares
+	updated.CreatedAt = task.CreatedAt
+	updated.Version = task.Version + 1
+	updated.UpdatedAt = time.Now()
+	if err := repo.Update(ctx, updated); err != nil {
+		return Task{}, Task{}, err
+	}
+	return task, updated, nil
+}
+
+func (s *TaskService) Delete(ctx context.Context, subject, id string, ifMatch IfMatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commit(ctx, func(tx TaskRepository) (pendingEvent, error) {
+		deleted, err := s.delete(ctx, tx, subject, id, ifMatch)
+		return pendingEvent{typ: EventDeleted, task: deleted}, err
+	})
 }
 
-func (s *TaskService) Delete(id string) bool {
+// delete возвращает удалённую задачу
+func (s *TaskService) delete(ctx context.Context, repo TaskRepository, subject, id string, ifMatch IfMatch) (Task, error) {
+	task, err := access(ctx, repo, subject, id, Task.CanManage)
+	if err != nil {
+		return Task{}, err
+	}
+	if !ifMatch.Allows(task.Version) {
+		return Task{}, ErrPreconditionFailed
+	}
+	if err := repo.Delete(ctx, id); err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// Share выдаёт пользователю with доступ к задаче или меняет уровень уже выданного
+func (s *TaskService) Share(ctx context.Context, subject, id, with string, perm Permission) (Task, error) {
+	if with == "" || !perm.Valid() {
+		return Task{}, ErrInvalidShare
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	_, ok := s.tasks[id]
-	if ok {
-		delete(s.tasks, id)
+	var task Task
+	err := s.commit(ctx, func(tx TaskRepository) (pendingEvent, error) {
+		before, err := access(ctx, tx, subject, id, Task.CanManage)
+		if err != nil {
+			return pendingEvent{}, err
+		}
+		if with == before.Owner {
+			return pendingEvent{}, ErrInvalidShare
+		}
+		task = before
+		// Копируем map, чтобы не менять уже выданные копии задачи
+		shares := make(map[string]Permission, len(task.Shares)+1)
+		for k, v := range task.Shares {
+			shares[k] = v
+		}
+		shares[with] = perm
+		task.Shares = shares
+		task.Version++
+		task.UpdatedAt = time.Now()
+		return pendingEvent{typ: EventUpdated, before: &before, task: task}, tx.Update(ctx, task)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// Unshare отзывает доступ пользователя with к задаче
+func (s *TaskService) Unshare(ctx context.Context, subject, id, with string) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var task Task
+	err := s.commit(ctx, func(tx TaskRepository) (pendingEvent, error) {
+		before, err := access(ctx, tx, subject, id, Task.CanManage)
+		if err != nil {
+			return pendingEvent{}, err
+		}
+		if _, ok := before.Shares[with]; !ok {
+			return pendingEvent{}, ErrShareNotFound
+		}
+		task = before
+		shares := make(map[string]Permission, len(task.Shares))
+		for k, v := range task.Shares {
+			if k != with {
+				shares[k] = v
+			}
+		}
+		task.Shares = shares
+		task.Version++
+		task.UpdatedAt = time.Now()
+		return pendingEvent{typ: EventUpdated, before: &before, task: task}, tx.Update(ctx, task)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// access находит задачу и проверяет право subject на действие allowed.
+// Тем, кто не видит задачу, возвращается ErrTaskNotFound, а видящим её,
+// но без нужного права — ErrForbidden. Администратору разрешено любое действие.
+func access(ctx context.Context, repo TaskRepository, subject, id string, allowed func(Task, string) bool) (Task, error) {
+	task, err := repo.Get(ctx, id)
+	if err != nil {
+		return Task{}, err
+	}
+	if isAdmin(ctx) {
+		return task, nil
+	}
+	if !task.CanView(subject) {
+		return Task{}, ErrTaskNotFound
+	}
+	if !allowed(task, subject) {
+		return Task{}, ErrForbidden
 	}
-	return ok
+	return task, nil
 }
@@ -0,0 +1,254 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration — одна версия схемы с SQL для наката и отката
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status — состояние миграции в конкретной базе
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Placeholder формирует n-й (с единицы) параметр запроса в синтаксисе драйвера
+type Placeholder func(n int) string
+
+// Question — параметры вида ? (SQLite)
+func Question(int) string { return "?" }
+
+// Dollar — параметры вида $1 (PostgreSQL)
+func Dollar(n int) string { return "$" + strconv.Itoa(n) }
+
+// Lock берёт межпроцессную блокировку миграций на отдельном соединении conn и возвращает
+// функцию, которая её снимает. Пока блокировка у одного процесса, остальные ждут,
+// поэтому экземпляры сервиса, запущенные одновременно, не накатывают одну миграцию дважды.
+type Lock func(ctx context.Context, conn *sql.Conn) (unlock func() error, err error)
+
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load читает миграции из fsys: файлы вида 0001_name.up.sql и 0001_name.down.sql
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, path.Clean(e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator накатывает и откатывает миграции, запоминая применённые версии в schema_migrations
+type Migrator struct {
+	db          *sql.DB
+	migrations  []Migration
+	placeholder Placeholder
+	// lock сериализует Up и Down между процессами; nil — без блокировки
+	lock Lock
+}
+
+func New(db *sql.DB, fsys fs.FS, placeholder Placeholder, lock Lock) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:          db,
+		migrations:  migrations,
+		placeholder: placeholder,
+		lock:        lock,
+	}, nil
+}
+
+// locked выполняет fn под блокировкой миграций. Применённые версии читаются уже
+// под ней, чтобы не повторить миграцию, которую только что накатил другой процесс.
+func (m *Migrator) locked(ctx context.Context, fn func() error) error {
+	if m.lock == nil {
+		return fn()
+	}
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.Close()
+	unlock, err := m.lock(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	fnErr := fn()
+	if err := unlock(); err != nil && fnErr == nil {
+		return fmt.Errorf("release migration lock: %w", err)
+	}
+	return fnErr
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("read schema_migrations: %w", err)
+		}
+		applied[version], _ = time.Parse(time.RFC3339Nano, at)
+	}
+	return applied, rows.Err()
+}
+
+// Up применяет все ещё не применённые миграции по возрастанию версий
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			insert := fmt.Sprintf(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (%s, %s, %s)`,
+				m.placeholder(1), m.placeholder(2), m.placeholder(3))
+			err := m.inTx(ctx, mig.Up, insert, mig.Version, mig.Name, time.Now().UTC().Format(time.RFC3339Nano))
+			if err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает последнюю применённую миграцию. Если применённых нет, возвращает ok=false.
+func (m *Migrator) Down(ctx context.Context) (mig Migration, ok bool, err error) {
+	err = m.locked(ctx, func() error {
+		mig, ok, err = m.down(ctx)
+		return err
+	})
+	return mig, ok, err
+}
+
+func (m *Migrator) down(ctx context.Context) (Migration, bool, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return Migration{}, false, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return Migration{}, false, fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+		}
+		del := fmt.Sprintf(`DELETE FROM schema_migrations WHERE version = %s`, m.placeholder(1))
+		if err := m.inTx(ctx, mig.Down, del, mig.Version); err != nil {
+			return Migration{}, false, fmt.Errorf("revert migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		return mig, true, nil
+	}
+	return Migration{}, false, nil
+}
+
+// Status возвращает все известные миграции с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		statuses = append(statuses, Status{Migration: mig, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// inTx выполняет скрипт миграции и запись в schema_migrations в одной транзакции
+func (m *Migrator) inTx(ctx context.Context, script, bookkeeping string, args ...any) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_create_a.up.sql":   {Data: []byte(`CREATE TABLE a (id INTEGER PRIMARY KEY)`)},
+	"0001_create_a.down.sql": {Data: []byte(`DROP TABLE a`)},
+	"0002_create_b.up.sql":   {Data: []byte(`CREATE TABLE b (id INTEGER PRIMARY KEY)`)},
+	"0002_create_b.down.sql": {Data: []byte(`DROP TABLE b`)},
+}
+
+func openDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestUpUnderLockAppliesOnce запускает несколько мигрирующих «экземпляров» одновременно:
+// каждая миграция должна примениться ровно один раз, а остальные — увидеть её готовой
+func TestUpUnderLockAppliesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	var mu sync.Mutex
+	var held, calls int
+	lock := func(ctx context.Context, conn *sql.Conn) (func() error, error) {
+		mu.Lock()
+		held++
+		calls++
+		return func() error {
+			held--
+			mu.Unlock()
+			return nil
+		}, nil
+	}
+
+	const instances = 4
+	results := make([][]Migration, instances)
+	errs := make([]error, instances)
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		m, err := New(openDB(t, path), testMigrations, Question, lock)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = m.Up(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	applied := 0
+	for i := range results {
+		if errs[i] != nil {
+			t.Errorf("instance %d: Up: %v", i, errs[i])
+		}
+		applied += len(results[i])
+	}
+	if applied != 2 {
+		t.Errorf("migrations applied %d times in total, want 2", applied)
+	}
+	if calls != instances || held != 0 {
+		t.Errorf("lock taken %d times and left held %d times, want %d and 0", calls, held, instances)
+	}
+}
+
+func TestDownRevertsLatest(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(openDB(t, filepath.Join(t.TempDir(), "test.db")), testMigrations, Question, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	for _, want := range []int{2, 1} {
+		mig, ok, err := m.Down(ctx)
+		if err != nil || !ok || mig.Version != want {
+			t.Fatalf("Down = %d, ok %v, err %v; want %d", mig.Version, ok, err, want)
+		}
+	}
+	if _, ok, err := m.Down(ctx); err != nil || ok {
+		t.Fatalf("Down with nothing applied: ok %v, err %v", ok, err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Errorf("migration %d is still applied", s.Version)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+CREATE TABLE tasks (
+    id          TEXT PRIMARY KEY,
+    owner       TEXT NOT NULL,
+    title       TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    due_date    TEXT NOT NULL DEFAULT '',
+    done        BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_tasks_owner ON tasks (owner);
+
+CREATE TABLE task_shares (
+    task_id    TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    subject    TEXT NOT NULL,
+    permission TEXT NOT NULL CHECK (permission IN ('viewer', 'editor')),
+    PRIMARY KEY (task_id, subject)
+);
+
+CREATE INDEX idx_task_shares_subject ON task_shares (subject);
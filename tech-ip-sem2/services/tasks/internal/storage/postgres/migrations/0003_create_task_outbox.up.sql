@@ -0,0 +1,8 @@
+CREATE TABLE task_outbox (
+    seq         BIGSERIAL PRIMARY KEY,
+    event_key   TEXT NOT NULL UNIQUE,
+    type        TEXT NOT NULL,
+    task        JSONB NOT NULL,
+    previous    JSONB,
+    occurred_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP INDEX idx_tasks_due_at;
+ALTER TABLE tasks DROP COLUMN due_at;
@@ -0,0 +1,25 @@
+-- due_at — срок как момент времени для сортировки и фильтров; NULL — срок не задан.
+-- Дата без времени соответствует полуночи по UTC.
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMPTZ;
+
+-- Приводятся только значения в формате API (YYYY-MM-DD или RFC 3339): сроки
+-- в свободной форме из старых версий остаются в due_date, но без due_at.
+-- Несуществующая дата (2026-02-30) проходит проверку формата, но не приведение,
+-- поэтому ошибка приведения тоже даёт NULL, а не прерывает миграцию.
+CREATE FUNCTION pg_temp.due_at(due_date TEXT) RETURNS TIMESTAMPTZ AS $$
+BEGIN
+    IF due_date ~ '^\d{4}-\d{2}-\d{2}$' THEN
+        RETURN (due_date || 'T00:00:00Z')::timestamptz;
+    END IF;
+    IF due_date ~ '^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})$' THEN
+        RETURN due_date::timestamptz;
+    END IF;
+    RETURN NULL;
+EXCEPTION WHEN datetime_field_overflow OR invalid_datetime_format THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+UPDATE tasks SET due_at = pg_temp.due_at(due_date);
+
+CREATE INDEX idx_tasks_due_at ON tasks (due_at);
@@ -0,0 +1,27 @@
+CREATE TABLE webhooks (
+    id         TEXT PRIMARY KEY,
+    owner      TEXT NOT NULL,
+    url        TEXT NOT NULL,
+    events     JSONB NOT NULL,
+    secret     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_webhooks_owner ON webhooks (owner);
+
+CREATE TABLE webhook_deliveries (
+    seq         BIGSERIAL PRIMARY KEY,
+    webhook_id  TEXT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    delivery_id TEXT NOT NULL,
+    event_key   TEXT NOT NULL,
+    event_type  TEXT NOT NULL,
+    task_id     TEXT NOT NULL,
+    attempt     INTEGER NOT NULL,
+    status_code INTEGER NOT NULL,
+    error       TEXT NOT NULL,
+    duration_ns BIGINT NOT NULL,
+    at          TIMESTAMPTZ NOT NULL,
+    success     BOOLEAN NOT NULL
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, seq);
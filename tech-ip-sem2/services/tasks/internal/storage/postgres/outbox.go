@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// AppendEvent сохраняет событие в task_outbox; задача до и после изменения хранится в JSONB
+func (r *TaskRepository) AppendEvent(ctx context.Context, ev service.OutboxEvent) error {
+	task, err := json.Marshal(ev.Task)
+	if err != nil {
+		return fmt.Errorf("encode outbox task: %w", err)
+	}
+	var previous any
+	if ev.Before != nil {
+		data, err := json.Marshal(ev.Before)
+		if err != nil {
+			return fmt.Errorf("encode outbox task: %w", err)
+		}
+		previous = string(data)
+	}
+	_, err = r.conn().ExecContext(ctx, `INSERT INTO task_outbox (event_key, type, task, previous, occurred_at) VALUES ($1, $2, $3, $4, $5)`,
+		ev.Key, string(ev.Type), string(task), previous, ev.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("insert outbox event: %w", err)
+	}
+	return nil
+}
+
+func (r *TaskRepository) PendingEvents(ctx context.Context, limit int) ([]service.OutboxEvent, error) {
+	rows, err := r.conn().QueryContext(ctx,
+		`SELECT event_key, type, task, previous, occurred_at FROM task_outbox ORDER BY seq LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("select outbox: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]service.OutboxEvent, 0)
+	for rows.Next() {
+		var ev service.OutboxEvent
+		var typ string
+		var task, previous []byte
+		if err := rows.Scan(&ev.Key, &typ, &task, &previous, &ev.OccurredAt); err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", err)
+		}
+		ev.Type = service.EventType(typ)
+		if err := json.Unmarshal(task, &ev.Task); err != nil {
+			return nil, fmt.Errorf("decode outbox event %s: %w", ev.Key, err)
+		}
+		if previous != nil {
+			ev.Before = new(service.Task)
+			if err := json.Unmarshal(previous, ev.Before); err != nil {
+				return nil, fmt.Errorf("decode outbox event %s: %w", ev.Key, err)
+			}
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+func (r *TaskRepository) AckEvents(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]any, len(keys))
+	placeholders := make([]string, len(keys))
+	for i, k := range keys {
+		args[i] = k
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	if _, err := r.conn().ExecContext(ctx, `DELETE FROM task_outbox WHERE event_key IN (`+strings.Join(placeholders, ", ")+`)`, args...); err != nil {
+		return fmt.Errorf("delete outbox events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// ListPage выбирает страницу списка задач одним запросом: фильтры, порядок,
+// позиция курсора и лимит выполняются в PostgreSQL (см. service.TaskPager).
+// Строки сравниваются в collation "C", то есть побайтно, как в хранилище в памяти,
+// независимо от локали базы.
+func (r *TaskRepository) ListPage(ctx context.Context, subject string, q service.PageQuery) ([]service.Task, error) {
+	var args []any
+	// arg добавляет параметр запроса и возвращает ссылку на него; по номеру его можно использовать повторно
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	s := arg(subject)
+	where := []string{`(owner = ` + s + ` OR id IN (SELECT task_id FROM task_shares WHERE subject = ` + s + `))`}
+	if q.Done != nil {
+		where = append(where, `done = `+arg(*q.Done))
+	}
+	if q.Q != "" {
+		where = append(where, `strpos(lower(title), lower(`+arg(q.Q)+`)) > 0`)
+	}
+	if len(q.Tags) > 0 {
+		sub := `SELECT task_id FROM task_tags WHERE tag = ANY(` + arg(q.Tags) + `)`
+		if !q.AnyTag {
+			sub += ` GROUP BY task_id HAVING COUNT(*) = ` + arg(len(q.Tags))
+		}
+		where = append(where, `id IN (`+sub+`)`)
+	}
+	// Сравнение с NULL ложно, поэтому задачи без срока в выборку по сроку не попадают
+	if !q.DueBefore.IsZero() {
+		where = append(where, `due_at < `+arg(q.DueBefore))
+	}
+	if !q.DueAfter.IsZero() {
+		where = append(where, `due_at > `+arg(q.DueAfter))
+	}
+
+	var order string
+	switch q.Sort {
+	case service.SortTitle:
+		order = `title COLLATE "C", id COLLATE "C"`
+		if a := q.After; a != nil {
+			t, id := arg(a.Title), arg(a.ID)
+			where = append(where, fmt.Sprintf(
+				`(title COLLATE "C" > %s OR (title = %s AND id COLLATE "C" > %s))`, t, t, id))
+		}
+	case service.SortDueDate:
+		order = `due_at ASC NULLS LAST, id COLLATE "C"`
+		if a := q.After; a != nil {
+			id := arg(a.ID)
+			if a.DueDate.IsZero() {
+				where = append(where, `(due_at IS NULL AND id COLLATE "C" > `+id+`)`)
+			} else {
+				d := arg(a.DueDate.Time)
+				where = append(where, fmt.Sprintf(
+					`(due_at IS NULL OR due_at > %s OR (due_at = %s AND id COLLATE "C" > %s))`, d, d, id))
+			}
+		}
+	default:
+		order = `created_at, id COLLATE "C"`
+		if a := q.After; a != nil {
+			c, id := arg(a.CreatedAt), arg(a.ID)
+			where = append(where, fmt.Sprintf(
+				`(created_at > %s OR (created_at = %s AND id COLLATE "C" > %s))`, c, c, id))
+		}
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE ` + strings.Join(where, ` AND `) +
+		` ORDER BY ` + order + ` LIMIT ` + arg(q.Limit)
+	rows, err := r.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]service.Task, 0, q.Limit)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select tasks: %w", err)
+	}
+	if err := r.attach(ctx, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// attach загружает списки доступа и теги выбранных задач
+func (r *TaskRepository) attach(ctx context.Context, tasks []service.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+
+	shares, err := r.loadShares(ctx, `SELECT task_id, subject, permission FROM task_shares WHERE task_id = ANY($1)`, ids)
+	if err != nil {
+		return err
+	}
+	tags, err := r.loadTags(ctx, `SELECT task_id, tag FROM task_tags WHERE task_id = ANY($1) ORDER BY tag`, ids)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].Shares = shares[tasks[i].ID]
+		tasks[i].Tags = tags[tasks[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+CREATE TABLE tasks (
+    id          TEXT PRIMARY KEY,
+    owner       TEXT NOT NULL,
+    title       TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    due_date    TEXT NOT NULL DEFAULT '',
+    done        INTEGER NOT NULL DEFAULT 0,
+    created_at  TEXT NOT NULL,
+    updated_at  TEXT NOT NULL
+);
+
+CREATE INDEX idx_tasks_owner ON tasks (owner);
+
+CREATE TABLE task_shares (
+    task_id    TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    subject    TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (task_id, subject)
+);
+
+CREATE INDEX idx_task_shares_subject ON task_shares (subject);
@@ -0,0 +1,8 @@
+CREATE TABLE task_outbox (
+    seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_key   TEXT NOT NULL UNIQUE,
+    type        TEXT NOT NULL,
+    task        TEXT NOT NULL,
+    previous    TEXT,
+    occurred_at TEXT NOT NULL
+);
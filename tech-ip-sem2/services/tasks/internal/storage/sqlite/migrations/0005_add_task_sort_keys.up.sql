@@ -0,0 +1,32 @@
+-- Метки времени приводятся к виду фиксированной ширины (9 знаков после запятой, UTC),
+-- чтобы сравнение строк совпадало со сравнением моментов времени
+UPDATE tasks SET
+    created_at = CASE
+        WHEN instr(created_at, '.') = 0 THEN substr(created_at, 1, 19) || '.000000000Z'
+        ELSE substr(created_at, 1, 20) || substr(substr(created_at, 21, length(created_at) - 21) || '000000000', 1, 9) || 'Z'
+    END,
+    updated_at = CASE
+        WHEN instr(updated_at, '.') = 0 THEN substr(updated_at, 1, 19) || '.000000000Z'
+        ELSE substr(updated_at, 1, 20) || substr(substr(updated_at, 21, length(updated_at) - 21) || '000000000', 1, 9) || 'Z'
+    END;
+
+-- due_at — срок как момент времени для сортировки и фильтров; NULL — срок не задан.
+-- Дата без времени соответствует полуночи по UTC.
+ALTER TABLE tasks ADD COLUMN due_at TEXT;
+
+-- Приводятся только значения в формате API (YYYY-MM-DD или RFC 3339): сроки
+-- в свободной форме из старых версий остаются в due_date, но без due_at.
+-- Сравнение с date() отсекает несуществующие даты (2026-02-30); strftime переводит
+-- момент со смещением в UTC и сохраняет доли секунды с точностью до миллисекунд.
+UPDATE tasks SET due_at = CASE
+    WHEN due_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]'
+        AND date(due_date) = due_date
+        THEN due_date || 'T00:00:00.000000000Z'
+    WHEN due_date GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]*'
+        AND (due_date GLOB '*Z' OR due_date GLOB '*[+-][0-9][0-9]:[0-9][0-9]')
+        AND date(substr(due_date, 1, 10)) = substr(due_date, 1, 10)
+        THEN strftime('%Y-%m-%dT%H:%M:%f', due_date) || '000000Z'
+    ELSE NULL
+END;
+
+CREATE INDEX idx_tasks_due_at ON tasks (due_at);
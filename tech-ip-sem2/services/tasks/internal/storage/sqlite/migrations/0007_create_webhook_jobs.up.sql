@@ -0,0 +1,13 @@
+CREATE TABLE webhook_jobs (
+    id         TEXT PRIMARY KEY,
+    webhook_id TEXT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_key  TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    task_id    TEXT NOT NULL,
+    payload    TEXT NOT NULL,
+    attempt    INTEGER NOT NULL DEFAULT 0,
+    next_at    TEXT NOT NULL,
+    UNIQUE (webhook_id, event_key)
+);
+
+CREATE INDEX idx_webhook_jobs_next_at ON webhook_jobs (next_at);
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// ListPage выбирает страницу списка задач одним запросом: фильтры, порядок,
+// позиция курсора и лимит выполняются в SQLite (см. service.TaskPager)
+func (r *TaskRepository) ListPage(ctx context.Context, subject string, q service.PageQuery) ([]service.Task, error) {
+	var args []any
+	// arg добавляет параметр запроса и возвращает ссылку на него; по номеру его можно использовать повторно
+	arg := func(v any) string {
+		args = append(args, v)
+		return "?" + strconv.Itoa(len(args))
+	}
+
+	s := arg(subject)
+	where := []string{`(owner = ` + s + ` OR id IN (SELECT task_id FROM task_shares WHERE subject = ` + s + `))`}
+	if q.Done != nil {
+		where = append(where, `done = `+arg(*q.Done))
+	}
+	if q.Q != "" {
+		// lower() в SQLite меняет регистр только у ASCII, go_lower — у всех букв, как поиск в памяти
+		where = append(where, `instr(go_lower(title), `+arg(strings.ToLower(q.Q))+`) > 0`)
+	}
+	if len(q.Tags) > 0 {
+		tags := make([]string, 0, len(q.Tags))
+		for _, tag := range q.Tags {
+			tags = append(tags, arg(tag))
+		}
+		sub := `SELECT task_id FROM task_tags WHERE tag IN (` + strings.Join(tags, ", ") + `)`
+		if !q.AnyTag {
+			sub += ` GROUP BY task_id HAVING COUNT(*) = ` + arg(len(q.Tags))
+		}
+		where = append(where, `id IN (`+sub+`)`)
+	}
+	// Сравнение с NULL ложно, поэтому задачи без срока в выборку по сроку не попадают
+	if !q.DueBefore.IsZero() {
+		where = append(where, `due_at < `+arg(formatTime(q.DueBefore)))
+	}
+	if !q.DueAfter.IsZero() {
+		where = append(where, `due_at > `+arg(formatTime(q.DueAfter)))
+	}
+
+	var order string
+	switch q.Sort {
+	case service.SortTitle:
+		order = `title, id`
+		if a := q.After; a != nil {
+			t, id := arg(a.Title), arg(a.ID)
+			where = append(where, fmt.Sprintf(`(title > %s OR (title = %s AND id > %s))`, t, t, id))
+		}
+	case service.SortDueDate:
+		// Задачи без срока идут в конце
+		order = `due_at IS NULL, due_at, id`
+		if a := q.After; a != nil {
+			id := arg(a.ID)
+			if a.DueDate.IsZero() {
+				where = append(where, `(due_at IS NULL AND id > `+id+`)`)
+			} else {
+				d := arg(formatTime(a.DueDate.Time))
+				where = append(where, fmt.Sprintf(`(due_at IS NULL OR due_at > %s OR (due_at = %s AND id > %s))`, d, d, id))
+			}
+		}
+	default:
+		order = `created_at, id`
+		if a := q.After; a != nil {
+			c, id := arg(formatTime(a.CreatedAt)), arg(a.ID)
+			where = append(where, fmt.Sprintf(`(created_at > %s OR (created_at = %s AND id > %s))`, c, c, id))
+		}
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE ` + strings.Join(where, ` AND `) +
+		` ORDER BY ` + order + ` LIMIT ` + arg(q.Limit)
+	rows, err := r.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]service.Task, 0, q.Limit)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select tasks: %w", err)
+	}
+	if err := r.attach(ctx, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// attach загружает списки доступа и теги выбранных задач
+func (r *TaskRepository) attach(ctx context.Context, tasks []service.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]any, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	in := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	shares, err := r.loadShares(ctx, `SELECT task_id, subject, permission FROM task_shares WHERE task_id IN (`+in+`)`, ids...)
+	if err != nil {
+		return err
+	}
+	tags, err := r.loadTags(ctx, `SELECT task_id, tag FROM task_tags WHERE task_id IN (`+in+`) ORDER BY tag`, ids...)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].Shares = shares[tasks[i].ID]
+		tasks[i].Tags = tags[tasks[i].ID]
+	}
+	return nil
+}
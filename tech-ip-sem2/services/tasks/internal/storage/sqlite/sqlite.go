@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/migrate"
+	sqlitedriver "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// go_lower(s) переводит строку в нижний регистр по правилам Go: встроенная lower()
+// в SQLite без ICU понимает только ASCII, а поиск по заголовку должен находить и кириллицу
+func init() {
+	sqlitedriver.MustRegisterDeterministicScalarFunction("go_lower", 1, func(_ *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return strings.ToLower(v), nil
+		case []byte:
+			return strings.ToLower(string(v)), nil
+		default:
+			return v, nil
+		}
+	})
+}
+
+// Open открывает файл базы SQLite (драйвер на чистом Go, без cgo)
+func Open(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// SQLite допускает одного писателя; одно соединение избавляет от SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	return db, nil
+}
+
+// NewMigrator возвращает мигратор со встроенными в бинарник миграциями схемы SQLite.
+// Межпроцессная блокировка не используется: с файлом SQLite работает один экземпляр
+// сервиса, а его соединения сериализует SetMaxOpenConns(1).
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, sub, migrate.Question, nil)
+}
@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/storagetest"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
+)
+
+func TestTaskRepository(t *testing.T) {
+	storagetest.RunTaskRepositoryTests(t, func(t *testing.T) service.TaskRepository {
+		db, err := Open(filepath.Join(t.TempDir(), "tasks.db"))
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		m, err := NewMigrator(db)
+		if err != nil {
+			t.Fatalf("migrator: %v", err)
+		}
+		if _, err := m.Up(context.Background()); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		return NewTaskRepository(db)
+	})
+}
+
+func TestWebhookRepository(t *testing.T) {
+	storagetest.RunWebhookStoreTests(t, func(t *testing.T) webhook.Store {
+		db, err := Open(filepath.Join(t.TempDir(), "tasks.db"))
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		m, err := NewMigrator(db)
+		if err != nil {
+			t.Fatalf("migrator: %v", err)
+		}
+		if _, err := m.Up(context.Background()); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		return NewWebhookRepository(db)
+	})
+}
+
+func TestMigrationsUpDown(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("migrator: %v", err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if again, err := m.Up(ctx); err != nil || len(again) != 0 {
+		t.Fatalf("second Up applied %d migrations, err = %v", len(again), err)
+	}
+	for range applied {
+		if _, ok, err := m.Down(ctx); err != nil || !ok {
+			t.Fatalf("Down: ok = %v, err = %v", ok, err)
+		}
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Errorf("migration %d_%s still applied after full rollback", s.Version, s.Name)
+		}
+	}
+	// Откат полностью обратим: схема накатывается заново
+	if reapplied, err := m.Up(ctx); err != nil || len(reapplied) != len(applied) {
+		t.Fatalf("Up after rollback applied %d of %d, err = %v", len(reapplied), len(applied), err)
+	}
+}
+
+// TestSortKeysMigrationBackfill проверяет, что миграция 0005 переводит строки,
+// записанные прежним кодом, в сортируемый формат
+func TestSortKeysMigrationBackfill(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("migrator: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	// Откатываем схему до состояния перед 0005
+	for {
+		mig, ok, err := m.Down(ctx)
+		if err != nil || !ok {
+			t.Fatalf("Down: ok = %v, err = %v", ok, err)
+		}
+		if mig.Version == 5 {
+			break
+		}
+	}
+
+	// Прежний формат: RFC3339Nano без завершающих нулей, срок строкой — в том числе
+	// со смещением, в свободной форме и с несуществующей датой
+	for _, row := range []struct{ id, due, created string }{
+		{"t1", "", "2026-01-01T10:00:00.5Z"},
+		{"t2", "2026-03-01", "2026-01-01T10:00:00.25Z"},
+		{"t3", "2026-02-28T21:00:00Z", "2026-01-01T10:00:00Z"},
+		{"t4", "next friday", "2026-01-01T11:00:00Z"},
+		{"t5", "2026-03-01T03:00:00.5+05:00", "2026-01-01T12:00:00Z"},
+		{"t6", "2026-02-30", "2026-01-01T13:00:00Z"},
+	} {
+		_, err := db.ExecContext(ctx, `INSERT INTO tasks (id, owner, title, due_date, created_at, updated_at)
+			VALUES (?, 'alice', ?, ?, ?, ?)`, row.id, row.id, row.due, row.created, row.created)
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	repo := NewTaskRepository(db)
+	for _, tt := range []struct {
+		sort string
+		want []string
+	}{
+		// Строкой "…00.5Z" > "…00.25Z" > "…00Z" сравнивались бы наоборот
+		{service.SortCreatedAt, []string{"t3", "t2", "t1", "t4", "t5", "t6"}},
+		// t5 — 2026-02-28T22:00:00.5Z; сроки, которые не разбираются, идут как незаданные
+		{service.SortDueDate, []string{"t3", "t5", "t2", "t1", "t4", "t6"}},
+	} {
+		tasks, err := repo.ListPage(ctx, "alice", service.PageQuery{ListQuery: service.ListQuery{Sort: tt.sort, Limit: 10}})
+		if err != nil {
+			t.Fatalf("ListPage: %v", err)
+		}
+		var got []string
+		for _, task := range tasks {
+			got = append(got, task.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("sort %s: ids = %v, want %v", tt.sort, got, tt.want)
+		}
+	}
+	task, err := repo.Get(ctx, "t1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if want := time.Date(2026, 1, 1, 10, 0, 0, 5e8, time.UTC); !task.CreatedAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", task.CreatedAt, want)
+	}
+	// Старый срок читается и сохраняется без потерь
+	legacy, err := repo.Get(ctx, "t4")
+	if err != nil {
+		t.Fatalf("Get legacy task: %v", err)
+	}
+	if got := legacy.DueDate.String(); got != "next friday" {
+		t.Errorf("legacy due_date = %q, want %q", got, "next friday")
+	}
+	legacy.Title, legacy.Version = "renamed", legacy.Version+1
+	if err := repo.Update(ctx, legacy); err != nil {
+		t.Fatalf("Update legacy task: %v", err)
+	}
+	if got, err := repo.Get(ctx, "t4"); err != nil || got.DueDate.String() != "next friday" {
+		t.Errorf("legacy due_date after update = %q, %v", got.DueDate, err)
+	}
+}
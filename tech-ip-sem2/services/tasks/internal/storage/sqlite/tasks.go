@@ -0,0 +1,285 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+const taskColumns = `id, owner, title, description, due_date, done, version, created_at, updated_at`
+
+// TaskRepository хранит задачи в SQLite
+type TaskRepository struct {
+	db *sql.DB
+	// tx задан у репозитория, переданного в InTx: все его запросы идут в этой транзакции
+	tx *sql.Tx
+}
+
+func NewTaskRepository(db *sql.DB) *TaskRepository {
+	return &TaskRepository{db: db}
+}
+
+// querier — общая часть *sql.DB и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (r *TaskRepository) conn() querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
+}
+
+// write выполняет fn в транзакции InTx, если она открыта, иначе в собственной
+func (r *TaskRepository) write(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TaskRepository) InTx(ctx context.Context, fn func(tx service.TaskRepository) error) error {
+	return r.write(ctx, func(tx *sql.Tx) error {
+		return fn(&TaskRepository{db: r.db, tx: tx})
+	})
+}
+
+func (r *TaskRepository) Create(ctx context.Context, task service.Task) error {
+	return r.write(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `INSERT INTO tasks (`+taskColumns+`, due_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO NOTHING`,
+			task.ID, task.Owner, task.Title, task.Description, task.DueDate, task.Done, task.Version,
+			formatTime(task.CreatedAt), formatTime(task.UpdatedAt), dueAt(task.DueDate))
+		if err != nil {
+			return fmt.Errorf("insert task: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return service.ErrTaskExists
+		}
+		if err := insertShares(ctx, tx, task); err != nil {
+			return err
+		}
+		return insertTags(ctx, tx, task)
+	})
+}
+
+func (r *TaskRepository) Get(ctx context.Context, id string) (service.Task, error) {
+	row := r.conn().QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id)
+	task, err := scanTask(row)
+	if err == sql.ErrNoRows {
+		return service.Task{}, service.ErrTaskNotFound
+	}
+	if err != nil {
+		return service.Task{}, fmt.Errorf("select task: %w", err)
+	}
+
+	shares, err := r.loadShares(ctx, `SELECT task_id, subject, permission FROM task_shares WHERE task_id = ?`, id)
+	if err != nil {
+		return service.Task{}, err
+	}
+	task.Shares = shares[id]
+
+	tags, err := r.loadTags(ctx, `SELECT task_id, tag FROM task_tags WHERE task_id = ? ORDER BY tag`, id)
+	if err != nil {
+		return service.Task{}, err
+	}
+	task.Tags = tags[id]
+	return task, nil
+}
+
+func (r *TaskRepository) ListVisible(ctx context.Context, subject string) ([]service.Task, error) {
+	const visible = `owner = ? OR id IN (SELECT task_id FROM task_shares WHERE subject = ?)`
+
+	rows, err := r.conn().QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE `+visible, subject, subject)
+	if err != nil {
+		return nil, fmt.Errorf("select tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]service.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select tasks: %w", err)
+	}
+
+	shares, err := r.loadShares(ctx,
+		`SELECT task_id, subject, permission FROM task_shares WHERE task_id IN (SELECT id FROM tasks WHERE `+visible+`)`,
+		subject, subject)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := r.loadTags(ctx,
+		`SELECT task_id, tag FROM task_tags WHERE task_id IN (SELECT id FROM tasks WHERE `+visible+`) ORDER BY tag`,
+		subject, subject)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		tasks[i].Shares = shares[tasks[i].ID]
+		tasks[i].Tags = tags[tasks[i].ID]
+	}
+	return tasks, nil
+}
+
+func (r *TaskRepository) Update(ctx context.Context, task service.Task) error {
+	return r.write(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE tasks
+			SET owner = ?, title = ?, description = ?, due_date = ?, due_at = ?, done = ?, version = ?, created_at = ?, updated_at = ?
+			WHERE id = ? AND version = ?`,
+			task.Owner, task.Title, task.Description, task.DueDate, dueAt(task.DueDate), task.Done, task.Version,
+			formatTime(task.CreatedAt), formatTime(task.UpdatedAt), task.ID, task.Version-1)
+		if err != nil {
+			return fmt.Errorf("update task: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return updateMissError(ctx, tx, task.ID)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM task_shares WHERE task_id = ?`, task.ID); err != nil {
+			return fmt.Errorf("delete shares: %w", err)
+		}
+		if err := insertShares(ctx, tx, task); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM task_tags WHERE task_id = ?`, task.ID); err != nil {
+			return fmt.Errorf("delete tags: %w", err)
+		}
+		return insertTags(ctx, tx, task)
+	})
+}
+
+func (r *TaskRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.conn().ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete task: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return service.ErrTaskNotFound
+	}
+	return nil
+}
+
+// updateMissError объясняет, почему UPDATE не затронул строк: задачи нет или её версия уже другая
+func updateMissError(ctx context.Context, tx *sql.Tx, id string) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, `SELECT 1 FROM tasks WHERE id = ?`, id).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return service.ErrTaskNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("select task: %w", err)
+	}
+	return service.ErrVersionConflict
+}
+
+func insertShares(ctx context.Context, tx *sql.Tx, task service.Task) error {
+	for subject, perm := range task.Shares {
+		_, err := tx.ExecContext(ctx, `INSERT INTO task_shares (task_id, subject, permission) VALUES (?, ?, ?)`,
+			task.ID, subject, string(perm))
+		if err != nil {
+			return fmt.Errorf("insert share: %w", err)
+		}
+	}
+	return nil
+}
+
+func insertTags(ctx context.Context, tx *sql.Tx, task service.Task) error {
+	for _, tag := range task.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_tags (task_id, tag) VALUES (?, ?)`, task.ID, tag); err != nil {
+			return fmt.Errorf("insert tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadTags возвращает теги задач, сгруппированные по id задачи, в порядке выборки
+func (r *TaskRepository) loadTags(ctx context.Context, query string, args ...any) (map[string][]string, error) {
+	rows, err := r.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var taskID, tag string
+		if err := rows.Scan(&taskID, &tag); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags[taskID] = append(tags[taskID], tag)
+	}
+	return tags, rows.Err()
+}
+
+// loadShares возвращает списки доступа, сгруппированные по id задачи
+func (r *TaskRepository) loadShares(ctx context.Context, query string, args ...any) (map[string]map[string]service.Permission, error) {
+	rows, err := r.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := make(map[string]map[string]service.Permission)
+	for rows.Next() {
+		var taskID, subject, perm string
+		if err := rows.Scan(&taskID, &subject, &perm); err != nil {
+			return nil, fmt.Errorf("scan share: %w", err)
+		}
+		if shares[taskID] == nil {
+			shares[taskID] = make(map[string]service.Permission)
+		}
+		shares[taskID][subject] = service.Permission(perm)
+	}
+	return shares, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(s scanner) (service.Task, error) {
+	var t service.Task
+	var createdAt, updatedAt string
+	if err := s.Scan(&t.ID, &t.Owner, &t.Title, &t.Description, &t.DueDate, &t.Done, &t.Version, &createdAt, &updatedAt); err != nil {
+		return service.Task{}, err
+	}
+	t.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	t.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+	return t, nil
+}
+
+// timeLayout — RFC 3339 с наносекундами фиксированной ширины: для времени в UTC
+// сравнение строк совпадает со сравнением моментов, на этом держатся сортировка и курсоры
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// dueAt — значение колонки due_at: срок в формате formatTime или NULL, если срока нет
+func dueAt(d service.DueDate) any {
+	if d.IsZero() {
+		return nil
+	}
+	return formatTime(d.Time)
+}
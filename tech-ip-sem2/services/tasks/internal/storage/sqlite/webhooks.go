@@ -0,0 +1,216 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
+)
+
+const (
+	webhookColumns  = `id, owner, url, events, secret, created_at`
+	deliveryColumns = `delivery_id, event_key, event_type, task_id, attempt, status_code, error, duration_ns, at, success`
+	jobColumns      = `id, webhook_id, event_key, event_type, task_id, payload, attempt, next_at`
+)
+
+// WebhookRepository хранит вебхуки, журнал и очередь доставок в SQLite
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, hook webhook.Webhook) error {
+	events, err := json.Marshal(hook.Events)
+	if err != nil {
+		return fmt.Errorf("encode webhook events: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		hook.ID, hook.Owner, hook.URL, string(events), hook.Secret, formatTime(hook.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) GetWebhook(ctx context.Context, id string) (webhook.Webhook, error) {
+	hook, err := scanWebhook(r.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return webhook.Webhook{}, webhook.ErrNotFound
+	}
+	if err != nil {
+		return webhook.Webhook{}, fmt.Errorf("select webhook: %w", err)
+	}
+	return hook, nil
+}
+
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, owner string) ([]webhook.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE ? = '' OR owner = ? ORDER BY id`, owner, owner)
+	if err != nil {
+		return nil, fmt.Errorf("select webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := make([]webhook.Webhook, 0)
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return webhook.ErrNotFound
+	}
+	return nil
+}
+
+// AddDelivery добавляет попытку и удаляет из журнала записи старше последних keep
+func (r *WebhookRepository) AddDelivery(ctx context.Context, webhookID string, d webhook.Delivery, keep int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, `+deliveryColumns+`)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = ?)`,
+		webhookID, d.ID, d.EventKey, string(d.EventType), d.TaskID, d.Attempt, d.StatusCode, d.Error,
+		int64(d.Duration), formatTime(d.At), d.Success, webhookID)
+	if err != nil {
+		return fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE webhook_id = ? AND seq <= (
+		SELECT seq FROM webhook_deliveries WHERE webhook_id = ? ORDER BY seq DESC LIMIT 1 OFFSET ?)`,
+		webhookID, webhookID, keep)
+	if err != nil {
+		return fmt.Errorf("trim webhook deliveries: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string) ([]webhook.Delivery, error) {
+	if _, err := r.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE webhook_id = ? ORDER BY seq DESC`, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("select webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]webhook.Delivery, 0)
+	for rows.Next() {
+		var d webhook.Delivery
+		var typ, at string
+		var duration int64
+		if err := rows.Scan(&d.ID, &d.EventKey, &typ, &d.TaskID, &d.Attempt, &d.StatusCode, &d.Error, &duration, &at, &d.Success); err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		d.EventType = service.EventType(typ)
+		d.Duration = time.Duration(duration)
+		d.At, _ = time.Parse(time.RFC3339Nano, at)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(s scanner) (webhook.Webhook, error) {
+	var hook webhook.Webhook
+	var events, createdAt string
+	if err := s.Scan(&hook.ID, &hook.Owner, &hook.URL, &events, &hook.Secret, &createdAt); err != nil {
+		return webhook.Webhook{}, err
+	}
+	if err := json.Unmarshal([]byte(events), &hook.Events); err != nil {
+		return webhook.Webhook{}, fmt.Errorf("decode webhook %s events: %w", hook.ID, err)
+	}
+	hook.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	return hook, nil
+}
+
+// EnqueueJobs добавляет доставки одной транзакцией; уже стоящие в очереди и доставки
+// удалённых вебхуков пропускаются
+func (r *WebhookRepository) EnqueueJobs(ctx context.Context, jobs []webhook.Job) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, job := range jobs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO webhook_jobs (`+jobColumns+`)
+			SELECT ?, ?, ?, ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = ?)
+			ON CONFLICT DO NOTHING`,
+			job.ID, job.WebhookID, job.EventKey, string(job.EventType), job.TaskID, string(job.Payload),
+			job.Attempt, formatTime(job.NextAt), job.WebhookID)
+		if err != nil {
+			return fmt.Errorf("insert webhook job: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimJobs откладывает наступившие доставки одним UPDATE, поэтому два доставщика
+// не заберут одну доставку
+func (r *WebhookRepository) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Job, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE webhook_jobs SET next_at = ? WHERE id IN (
+		SELECT id FROM webhook_jobs WHERE next_at <= ? ORDER BY next_at, id LIMIT ?)
+		RETURNING `+jobColumns,
+		formatTime(now.Add(lease)), formatTime(now), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]webhook.Job, 0)
+	for rows.Next() {
+		var job webhook.Job
+		var typ, payload, nextAt string
+		if err := rows.Scan(&job.ID, &job.WebhookID, &job.EventKey, &typ, &job.TaskID, &payload, &job.Attempt, &nextAt); err != nil {
+			return nil, fmt.Errorf("scan webhook job: %w", err)
+		}
+		job.EventType = service.EventType(typ)
+		job.Payload = []byte(payload)
+		job.NextAt, _ = time.Parse(time.RFC3339Nano, nextAt)
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING не сохраняет порядок подзапроса
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+func (r *WebhookRepository) RetryJob(ctx context.Context, id string, attempt int, next time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE webhook_jobs SET attempt = ?, next_at = ? WHERE id = ?`,
+		attempt, formatTime(next), id)
+	if err != nil {
+		return fmt.Errorf("update webhook job: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) DeleteJob(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM webhook_jobs WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete webhook job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,527 @@
+// Package storagetest содержит общие проверки реализаций service.TaskRepository
+// и webhook.Store:
+// каждое хранилище прогоняет их на своей базе, чтобы все вели себя одинаково.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// Open возвращает пустое хранилище для одного теста
+type Open func(t *testing.T) service.TaskRepository
+
+// RunTaskRepositoryTests проверяет контракт service.TaskRepository
+func RunTaskRepositoryTests(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo service.TaskRepository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"Missing", testMissing},
+		{"UpdateChecksVersion", testUpdateChecksVersion},
+		{"ListVisible", testListVisible},
+		{"Delete", testDelete},
+		{"InTx", testInTx},
+		{"Outbox", testOutbox},
+		{"ListPages", testListPages},
+		{"ListCursorStability", testListCursorStability},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+// NewTask возвращает задачу первой версии с временем, которое без потерь
+// сохраняется во всех хранилищах (PostgreSQL хранит микросекунды)
+func NewTask(id, owner string) service.Task {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	return service.Task{
+		ID:        id,
+		Owner:     owner,
+		Title:     "task " + id,
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// MustCreate сохраняет задачи или завершает тест
+func MustCreate(t *testing.T, repo service.TaskRepository, tasks ...service.Task) {
+	t.Helper()
+	for _, task := range tasks {
+		if err := repo.Create(context.Background(), task); err != nil {
+			t.Fatalf("Create %s: %v", task.ID, err)
+		}
+	}
+}
+
+func testCreateAndGet(t *testing.T, repo service.TaskRepository) {
+	ctx := context.Background()
+	due, _ := service.ParseDueDate("2026-03-01T18:00:00+03:00")
+	task := NewTask("t1", "alice")
+	task.Description = "описание"
+	task.DueDate = due
+	task.Done = true
+	task.Shares = map[string]service.Permission{"bob": service.PermissionViewer, "carol": service.PermissionEditor}
+	task.Tags = []string{"home", "work"}
+	MustCreate(t, repo, task)
+
+	got, err := repo.Get(ctx, "t1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertTask(t, got, task)
+
+	dateOnly, _ := service.ParseDueDate("2026-03-01")
+	plain := NewTask("t2", "alice")
+	plain.DueDate = dateOnly
+	MustCreate(t, repo, plain)
+	if got, err = repo.Get(ctx, "t2"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertTask(t, got, plain)
+}
+
+func testCreateDuplicate(t *testing.T, repo service.TaskRepository) {
+	MustCreate(t, repo, NewTask("t1", "alice"))
+	dup := NewTask("t1", "bob")
+	if err := repo.Create(context.Background(), dup); !errors.Is(err, service.ErrTaskExists) {
+		t.Fatalf("Create duplicate: err = %v, want %v", err, service.ErrTaskExists)
+	}
+	got, err := repo.Get(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Owner != "alice" {
+		t.Fatalf("duplicate create replaced the task: owner %q", got.Owner)
+	}
+}
+
+func testMissing(t *testing.T, repo service.TaskRepository) {
+	ctx := context.Background()
+	if _, err := repo.Get(ctx, "nope"); !errors.Is(err, service.ErrTaskNotFound) {
+		t.Errorf("Get: err = %v, want %v", err, service.ErrTaskNotFound)
+	}
+	task := NewTask("nope", "alice")
+	task.Version = 2
+	if err := repo.Update(ctx, task); !errors.Is(err, service.ErrTaskNotFound) {
+		t.Errorf("Update: err = %v, want %v", err, service.ErrTaskNotFound)
+	}
+	if err := repo.Delete(ctx, "nope"); !errors.Is(err, service.ErrTaskNotFound) {
+		t.Errorf("Delete: err = %v, want %v", err, service.ErrTaskNotFound)
+	}
+}
+
+func testUpdateChecksVersion(t *testing.T, repo service.TaskRepository) {
+	ctx := context.Background()
+	task := NewTask("t1", "alice")
+	task.Shares = map[string]service.Permission{"bob": service.PermissionViewer}
+	task.Tags = []string{"old"}
+	MustCreate(t, repo, task)
+
+	next := task
+	next.Title = "renamed"
+	next.Version = 2
+	next.UpdatedAt = task.UpdatedAt.Add(time.Second)
+	next.Shares = map[string]service.Permission{"carol": service.PermissionEditor}
+	next.Tags = []string{"new", "newer"}
+	if err := repo.Update(ctx, next); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := repo.Get(ctx, "t1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertTask(t, got, next)
+
+	// Повтор той же версии — изменение поверх устаревшего состояния
+	stale := next
+	stale.Title = "stale"
+	if err := repo.Update(ctx, stale); !errors.Is(err, service.ErrVersionConflict) {
+		t.Fatalf("Update with stale version: err = %v, want %v", err, service.ErrVersionConflict)
+	}
+	if got, _ = repo.Get(ctx, "t1"); got.Title != "renamed" {
+		t.Fatalf("stale update was applied: title %q", got.Title)
+	}
+}
+
+func testListVisible(t *testing.T, repo service.TaskRepository) {
+	own := NewTask("t1", "alice")
+	shared := NewTask("t2", "bob")
+	shared.Shares = map[string]service.Permission{"alice": service.PermissionViewer}
+	foreign := NewTask("t3", "bob")
+	foreign.Shares = map[string]service.Permission{"carol": service.PermissionEditor}
+	MustCreate(t, repo, own, shared, foreign)
+
+	for subject, want := range map[string][]string{
+		"alice": {"t1", "t2"},
+		"bob":   {"t2", "t3"},
+		"carol": {"t3"},
+		"dave":  {},
+	} {
+		tasks, err := repo.ListVisible(context.Background(), subject)
+		if err != nil {
+			t.Fatalf("ListVisible(%s): %v", subject, err)
+		}
+		if got := ids(tasks); !slices.Equal(got, want) {
+			t.Errorf("ListVisible(%s) = %v, want %v", subject, got, want)
+		}
+	}
+
+	tasks, _ := repo.ListVisible(context.Background(), "alice")
+	for _, task := range tasks {
+		if task.ID == "t2" && task.Shares["alice"] != service.PermissionViewer {
+			t.Errorf("shared task listed without its shares: %v", task.Shares)
+		}
+	}
+}
+
+func testDelete(t *testing.T, repo service.TaskRepository) {
+	ctx := context.Background()
+	task := NewTask("t1", "alice")
+	task.Shares = map[string]service.Permission{"bob": service.PermissionViewer}
+	task.Tags = []string{"work"}
+	MustCreate(t, repo, task)
+
+	if err := repo.Delete(ctx, "t1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.Get(ctx, "t1"); !errors.Is(err, service.ErrTaskNotFound) {
+		t.Fatalf("Get after delete: err = %v, want %v", err, service.ErrTaskNotFound)
+	}
+	if tasks, _ := repo.ListVisible(ctx, "bob"); len(tasks) != 0 {
+		t.Fatalf("deleted task is still visible to bob: %v", ids(tasks))
+	}
+
+	// id удалённой задачи можно занять снова: доступ и теги прежней задачи не переходят к новой
+	MustCreate(t, repo, NewTask("t1", "carol"))
+	got, err := repo.Get(ctx, "t1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got.Shares) != 0 || len(got.Tags) != 0 {
+		t.Fatalf("recreated task inherited shares %v and tags %v", got.Shares, got.Tags)
+	}
+}
+
+func testInTx(t *testing.T, repo service.TaskRepository) {
+	ctx := context.Background()
+	MustCreate(t, repo, NewTask("t1", "alice"))
+
+	errBoom := errors.New("boom")
+	err := repo.InTx(ctx, func(tx service.TaskRepository) error {
+		if err := tx.Create(ctx, NewTask("t2", "alice")); err != nil {
+			return err
+		}
+		if err := tx.Delete(ctx, "t1"); err != nil {
+			return err
+		}
+		if err := tx.AppendEvent(ctx, newEvent("ev_rollback", "t2")); err != nil {
+			return err
+		}
+		// Внутри транзакции её изменения уже видны
+		if _, err := tx.Get(ctx, "t2"); err != nil {
+			return fmt.Errorf("read own write: %w", err)
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("InTx: err = %v, want %v", err, errBoom)
+	}
+	if _, err := repo.Get(ctx, "t2"); !errors.Is(err, service.ErrTaskNotFound) {
+		t.Errorf("rolled back create is visible: err = %v", err)
+	}
+	if _, err := repo.Get(ctx, "t1"); err != nil {
+		t.Errorf("rolled back delete was applied: %v", err)
+	}
+	if events, _ := repo.PendingEvents(ctx, 10); len(events) != 0 {
+		t.Errorf("rolled back event is in outbox: %v", keys(events))
+	}
+
+	err = repo.InTx(ctx, func(tx service.TaskRepository) error {
+		if err := tx.Create(ctx, NewTask("t2", "alice")); err != nil {
+			return err
+		}
+		return tx.AppendEvent(ctx, newEvent("ev_commit", "t2"))
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+	if _, err := repo.Get(ctx, "t2"); err != nil {
+		t.Errorf("committed create is missing: %v", err)
+	}
+	if events, _ := repo.PendingEvents(ctx, 10); !slices.Equal(keys(events), []string{"ev_commit"}) {
+		t.Errorf("outbox after commit = %v, want [ev_commit]", keys(events))
+	}
+}
+
+func testOutbox(t *testing.T, repo service.TaskRepository) {
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		if err := repo.AppendEvent(ctx, newEvent(fmt.Sprintf("ev_%d", i), "t1")); err != nil {
+			t.Fatalf("AppendEvent: %v", err)
+		}
+	}
+
+	events, err := repo.PendingEvents(ctx, 2)
+	if err != nil {
+		t.Fatalf("PendingEvents: %v", err)
+	}
+	if got := keys(events); !slices.Equal(got, []string{"ev_1", "ev_2"}) {
+		t.Fatalf("PendingEvents(2) = %v, want [ev_1 ev_2]", got)
+	}
+	ev := events[1]
+	if ev.Type != service.EventUpdated || ev.Task.ID != "t1" || ev.Task.Title != "after" ||
+		ev.Before == nil || ev.Before.Title != "before" || ev.OccurredAt.IsZero() {
+		t.Fatalf("event did not round-trip: %+v", ev)
+	}
+
+	if err := repo.AckEvents(ctx, []string{"ev_1", "ev_2", "ev_unknown"}); err != nil {
+		t.Fatalf("AckEvents: %v", err)
+	}
+	if events, _ = repo.PendingEvents(ctx, 10); !slices.Equal(keys(events), []string{"ev_3"}) {
+		t.Fatalf("outbox after ack = %v, want [ev_3]", keys(events))
+	}
+	if err := repo.AckEvents(ctx, nil); err != nil {
+		t.Fatalf("AckEvents(nil): %v", err)
+	}
+}
+
+// listFixture — задачи alice для проверок списка: совпадающие created_at и сроки,
+// сроки в разных часовых поясах и без времени, заголовки в разном регистре и на кириллице
+func listFixture(t *testing.T, repo service.TaskRepository) time.Time {
+	t.Helper()
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	task := func(id, owner, title string, created time.Duration, due string, done bool, tags ...string) service.Task {
+		task := NewTask(id, owner)
+		task.Title = title
+		task.CreatedAt = base.Add(created)
+		task.UpdatedAt = task.CreatedAt
+		task.Done = done
+		task.Tags = tags
+		if due != "" {
+			d, err := service.ParseDueDate(due)
+			if err != nil {
+				t.Fatal(err)
+			}
+			task.DueDate = d
+		}
+		return task
+	}
+	shared := task("b1", "bob", "Бета shared", 3*time.Second, "2026-04-01", false, "shop")
+	shared.Shares = map[string]service.Permission{"alice": service.PermissionViewer}
+	MustCreate(t, repo,
+		task("a1", "alice", "Купить молоко", 0, "", false, "home"),
+		task("a2", "alice", "купить хлеб", 0, "2026-03-01", false, "home", "shop"),
+		// Тот же момент, что полночь 2026-03-01 по UTC у a2
+		task("a3", "alice", "Alpha report", time.Second, "2026-03-01T03:00:00+03:00", false, "work"),
+		task("a4", "alice", "alpha draft", 2*time.Second, "2026-02-01T10:00:00Z", true, "shop", "work"),
+		task("a5", "alice", "Zeta", 2*time.Second, "", false),
+		shared,
+		task("b2", "bob", "hidden", 3*time.Second, "2026-01-01", false, "home"),
+		task("a6", "alice", "Альфа", 4*time.Second, "", false, "home", "work"),
+	)
+	return base
+}
+
+// listAll проходит список страницами по limit задач и возвращает id по порядку
+func listAll(t *testing.T, ts *service.TaskService, q service.ListQuery, limit int) []string {
+	t.Helper()
+	q.Limit = limit
+	var ids []string
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			t.Fatalf("pagination does not terminate: %v", ids)
+		}
+		page, err := ts.List(context.Background(), "alice", q)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(page.Tasks) > limit || (page.NextCursor != "" && len(page.Tasks) != limit) {
+			t.Fatalf("page of %d tasks with limit %d, next cursor %q", len(page.Tasks), limit, page.NextCursor)
+		}
+		ids = append(ids, pageIDs(page.Tasks)...)
+		if page.NextCursor == "" {
+			return ids
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func newTaskService(repo service.TaskRepository) *service.TaskService {
+	return service.NewTaskService(repo, service.UUIDv7IDs, service.NewEventBus(service.DefaultEventHistory))
+}
+
+func testListPages(t *testing.T, repo service.TaskRepository) {
+	listFixture(t, repo)
+	ts := newTaskService(repo)
+	done, notDone := true, false
+	due := func(s string) time.Time {
+		d, _ := time.Parse(time.RFC3339, s)
+		return d
+	}
+
+	tests := []struct {
+		name string
+		q    service.ListQuery
+		want []string
+	}{
+		{"by created_at", service.ListQuery{}, []string{"a1", "a2", "a3", "a4", "a5", "b1", "a6"}},
+		// Равные сроки упорядочены по id, задачи без срока — в конце
+		{"by due_date", service.ListQuery{Sort: service.SortDueDate}, []string{"a4", "a2", "a3", "b1", "a1", "a5", "a6"}},
+		// Заголовки сравниваются побайтно: заглавные латинские, строчные, затем кириллица
+		{"by title", service.ListQuery{Sort: service.SortTitle}, []string{"a3", "a5", "a4", "a6", "b1", "a1", "a2"}},
+		{"done", service.ListQuery{Done: &done}, []string{"a4"}},
+		{"not done by due_date", service.ListQuery{Done: &notDone, Sort: service.SortDueDate}, []string{"a2", "a3", "b1", "a1", "a5", "a6"}},
+		{"search cyrillic ignoring case", service.ListQuery{Q: "КУПИТЬ"}, []string{"a1", "a2"}},
+		{"search latin ignoring case", service.ListQuery{Q: "alpha", Sort: service.SortTitle}, []string{"a3", "a4"}},
+		{"all tags", service.ListQuery{Tags: []string{"home", "shop"}}, []string{"a2"}},
+		{"repeated tag", service.ListQuery{Tags: []string{"home", "HOME"}}, []string{"a1", "a2", "a6"}},
+		{"any tag", service.ListQuery{Tags: []string{"shop", "work"}, AnyTag: true}, []string{"a2", "a3", "a4", "b1", "a6"}},
+		{"due before", service.ListQuery{DueBefore: due("2026-03-01T00:00:00Z")}, []string{"a4"}},
+		{"due after", service.ListQuery{DueAfter: due("2026-02-15T00:00:00+03:00"), Sort: service.SortDueDate}, []string{"a2", "a3", "b1"}},
+		{"nothing matches", service.ListQuery{Q: "нет такой"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Любой размер страницы даёт ту же последовательность без пропусков и повторов
+			for _, limit := range []int{1, 2, 3, 5, service.MaxPageSize} {
+				if got := listAll(t, ts, tt.q, limit); !slices.Equal(got, tt.want) {
+					t.Errorf("limit %d: ids = %v, want %v", limit, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func testListCursorStability(t *testing.T, repo service.TaskRepository) {
+	ctx := context.Background()
+	base := listFixture(t, repo)
+	ts := newTaskService(repo)
+
+	tests := []struct {
+		name  string
+		sort  string
+		limit int
+		// first — первая страница, rest — продолжение по её курсору после вставки новых задач
+		first, rest []string
+	}{
+		{"created_at", service.SortCreatedAt, 3, []string{"a1", "a2", "a3"}, []string{"a4", "a5", "b1", "a6", "n2"}},
+		// Курсор на задаче без срока: продолжение идёт только по задачам без срока
+		{"due_date", service.SortDueDate, 5, []string{"a4", "a2", "a3", "b1", "a1"}, []string{"a5", "a6", "n2"}},
+	}
+	cursors := make([]string, len(tests))
+	for i, tt := range tests {
+		page, err := ts.List(ctx, "alice", service.ListQuery{Sort: tt.sort, Limit: tt.limit})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if got := pageIDs(page.Tasks); !slices.Equal(got, tt.first) {
+			t.Fatalf("%s: first page = %v, want %v", tt.name, got, tt.first)
+		}
+		cursors[i] = page.NextCursor
+	}
+
+	// Задачи перед курсором не сдвигают продолжение, задача после него в него попадает
+	early, _ := service.ParseDueDate("2025-01-01")
+	n1 := NewTask("n1", "alice")
+	n1.DueDate = early
+	a0 := NewTask("a0", "alice")
+	n2 := NewTask("n2", "alice")
+	for _, task := range []*service.Task{&n1, &a0} {
+		task.CreatedAt, task.UpdatedAt = base.Add(-time.Second), base.Add(-time.Second)
+	}
+	n2.CreatedAt, n2.UpdatedAt = base.Add(time.Minute), base.Add(time.Minute)
+	MustCreate(t, repo, n1, a0, n2)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := service.ListQuery{Sort: tt.sort, Limit: 2, Cursor: cursors[i]}
+			var rest []string
+			for q.Cursor != "" {
+				page, err := ts.List(ctx, "alice", q)
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				rest = append(rest, pageIDs(page.Tasks)...)
+				q.Cursor = page.NextCursor
+			}
+			if !slices.Equal(rest, tt.rest) {
+				t.Fatalf("continuation = %v, want %v", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func newEvent(key, taskID string) service.OutboxEvent {
+	before := NewTask(taskID, "alice")
+	before.Title = "before"
+	after := before
+	after.Title = "after"
+	after.Version = 2
+	return service.OutboxEvent{
+		Key:        key,
+		Type:       service.EventUpdated,
+		Task:       after,
+		Before:     &before,
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+}
+
+func assertTask(t *testing.T, got, want service.Task) {
+	t.Helper()
+	if got.ID != want.ID || got.Owner != want.Owner || got.Title != want.Title ||
+		got.Description != want.Description || got.Done != want.Done || got.Version != want.Version {
+		t.Errorf("task = %+v, want %+v", got, want)
+	}
+	if !got.DueDate.Equal(want.DueDate) {
+		t.Errorf("due date = %v, want %v", got.DueDate, want.DueDate)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("timestamps = %v / %v, want %v / %v", got.CreatedAt, got.UpdatedAt, want.CreatedAt, want.UpdatedAt)
+	}
+	if !maps.Equal(got.Shares, want.Shares) {
+		t.Errorf("shares = %v, want %v", got.Shares, want.Shares)
+	}
+	if !slices.Equal(got.Tags, want.Tags) {
+		t.Errorf("tags = %v, want %v", got.Tags, want.Tags)
+	}
+}
+
+func ids(tasks []service.Task) []string {
+	ids := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// pageIDs возвращает id задач в порядке выдачи
+func pageIDs(tasks []service.Task) []string {
+	ids := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+func keys(events []service.OutboxEvent) []string {
+	keys := make([]string, 0, len(events))
+	for _, ev := range events {
+		keys = append(keys, ev.Key)
+	}
+	return keys
+}
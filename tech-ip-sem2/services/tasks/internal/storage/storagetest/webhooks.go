@@ -0,0 +1,326 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
+)
+
+// OpenWebhooks возвращает пустое хранилище вебхуков для одного теста
+type OpenWebhooks func(t *testing.T) webhook.Store
+
+// RunWebhookStoreTests проверяет контракт webhook.Store
+func RunWebhookStoreTests(t *testing.T, open OpenWebhooks) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store webhook.Store)
+	}{
+		{"CreateAndGet", testWebhookCreateAndGet},
+		{"List", testWebhookList},
+		{"Delete", testWebhookDelete},
+		{"DeliveryLog", testDeliveryLog},
+		{"EnqueueJobs", testEnqueueJobs},
+		{"ClaimJobs", testClaimJobs},
+		{"RetryAndDeleteJob", testRetryAndDeleteJob},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, open(t))
+		})
+	}
+}
+
+func newWebhook(id, owner string) webhook.Webhook {
+	return webhook.Webhook{
+		ID:        id,
+		Owner:     owner,
+		URL:       "https://example.com/hooks/" + id,
+		Events:    []service.EventType{service.EventCreated, service.EventDeleted},
+		Secret:    "whsec_" + id,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+}
+
+func newDelivery(attempt int) webhook.Delivery {
+	return webhook.Delivery{
+		ID:         "d1",
+		EventKey:   "ev_1",
+		EventType:  service.EventCreated,
+		TaskID:     "t1",
+		Attempt:    attempt,
+		StatusCode: 500 + attempt,
+		Error:      fmt.Sprintf("unexpected status %d", 500+attempt),
+		Duration:   time.Duration(attempt) * time.Millisecond,
+		At:         time.Now().UTC().Truncate(time.Microsecond),
+	}
+}
+
+func newJob(id, webhookID, eventKey string, nextAt time.Time) webhook.Job {
+	return webhook.Job{
+		ID:        id,
+		WebhookID: webhookID,
+		EventKey:  eventKey,
+		EventType: service.EventCreated,
+		TaskID:    "t1",
+		Payload:   []byte(`{"event_id":"` + eventKey + `"}`),
+		NextAt:    nextAt.UTC().Truncate(time.Microsecond),
+	}
+}
+
+// claimIDs забирает наступившие к now доставки и возвращает их id по возрастанию
+func claimIDs(t *testing.T, store webhook.Store, now time.Time, limit int) []string {
+	t.Helper()
+	jobs, err := store.ClaimJobs(context.Background(), now, time.Minute, limit)
+	if err != nil {
+		t.Fatalf("ClaimJobs: %v", err)
+	}
+	ids := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func mustCreateWebhooks(t *testing.T, store webhook.Store, hooks ...webhook.Webhook) {
+	t.Helper()
+	for _, hook := range hooks {
+		if err := store.CreateWebhook(context.Background(), hook); err != nil {
+			t.Fatalf("CreateWebhook %s: %v", hook.ID, err)
+		}
+	}
+}
+
+func testWebhookCreateAndGet(t *testing.T, store webhook.Store) {
+	hook := newWebhook("wh_1", "alice")
+	mustCreateWebhooks(t, store, hook)
+
+	got, err := store.GetWebhook(context.Background(), hook.ID)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if !got.CreatedAt.Equal(hook.CreatedAt) {
+		t.Fatalf("CreatedAt = %v, want %v", got.CreatedAt, hook.CreatedAt)
+	}
+	got.CreatedAt = hook.CreatedAt
+	if !reflect.DeepEqual(got, hook) {
+		t.Fatalf("GetWebhook = %+v, want %+v", got, hook)
+	}
+	if _, err := store.GetWebhook(context.Background(), "wh_missing"); !errors.Is(err, webhook.ErrNotFound) {
+		t.Fatalf("GetWebhook missing: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testWebhookList(t *testing.T, store webhook.Store) {
+	mustCreateWebhooks(t, store, newWebhook("wh_3", "alice"), newWebhook("wh_1", "alice"), newWebhook("wh_2", "bob"))
+
+	tests := []struct {
+		owner string
+		want  []string
+	}{
+		{"alice", []string{"wh_1", "wh_3"}},
+		{"bob", []string{"wh_2"}},
+		{"carol", []string{}},
+		{"", []string{"wh_1", "wh_2", "wh_3"}},
+	}
+	for _, tt := range tests {
+		hooks, err := store.ListWebhooks(context.Background(), tt.owner)
+		if err != nil {
+			t.Fatalf("ListWebhooks(%q): %v", tt.owner, err)
+		}
+		got := make([]string, 0, len(hooks))
+		for _, hook := range hooks {
+			got = append(got, hook.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ListWebhooks(%q) = %v, want %v", tt.owner, got, tt.want)
+		}
+	}
+}
+
+func testWebhookDelete(t *testing.T, store webhook.Store) {
+	ctx := context.Background()
+	mustCreateWebhooks(t, store, newWebhook("wh_1", "alice"))
+	if err := store.AddDelivery(ctx, "wh_1", newDelivery(1), 10); err != nil {
+		t.Fatalf("AddDelivery: %v", err)
+	}
+
+	if err := store.DeleteWebhook(ctx, "wh_1"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if err := store.DeleteWebhook(ctx, "wh_1"); !errors.Is(err, webhook.ErrNotFound) {
+		t.Fatalf("second DeleteWebhook: err = %v, want ErrNotFound", err)
+	}
+	if _, err := store.ListDeliveries(ctx, "wh_1"); !errors.Is(err, webhook.ErrNotFound) {
+		t.Fatalf("ListDeliveries after delete: err = %v, want ErrNotFound", err)
+	}
+	// Доставка, завершившаяся после удаления вебхука, не сохраняется и не ломает доставщик
+	if err := store.AddDelivery(ctx, "wh_1", newDelivery(2), 10); err != nil {
+		t.Fatalf("AddDelivery after delete: %v", err)
+	}
+	// Журнал не переходит к вебхуку с тем же id
+	mustCreateWebhooks(t, store, newWebhook("wh_1", "alice"))
+	if log, err := store.ListDeliveries(ctx, "wh_1"); err != nil || len(log) != 0 {
+		t.Fatalf("ListDeliveries of recreated webhook = %d records, err = %v", len(log), err)
+	}
+}
+
+func testDeliveryLog(t *testing.T, store webhook.Store) {
+	ctx := context.Background()
+	mustCreateWebhooks(t, store, newWebhook("wh_1", "alice"), newWebhook("wh_2", "alice"))
+
+	const keep = 3
+	var added []webhook.Delivery
+	for attempt := 1; attempt <= 5; attempt++ {
+		d := newDelivery(attempt)
+		d.Success = attempt == 5
+		if err := store.AddDelivery(ctx, "wh_1", d, keep); err != nil {
+			t.Fatalf("AddDelivery %d: %v", attempt, err)
+		}
+		added = append(added, d)
+	}
+	if err := store.AddDelivery(ctx, "wh_2", newDelivery(1), keep); err != nil {
+		t.Fatalf("AddDelivery wh_2: %v", err)
+	}
+
+	log, err := store.ListDeliveries(ctx, "wh_1")
+	if err != nil {
+		t.Fatalf("ListDeliveries: %v", err)
+	}
+	// Остаются последние keep попыток, начиная с самой свежей
+	want := []webhook.Delivery{added[4], added[3], added[2]}
+	if len(log) != len(want) {
+		t.Fatalf("ListDeliveries = %d records, want %d", len(log), len(want))
+	}
+	for i := range want {
+		if !log[i].At.Equal(want[i].At) {
+			t.Errorf("delivery %d: At = %v, want %v", i, log[i].At, want[i].At)
+		}
+		log[i].At = want[i].At
+		if !reflect.DeepEqual(log[i], want[i]) {
+			t.Errorf("delivery %d = %+v, want %+v", i, log[i], want[i])
+		}
+	}
+
+	if other, err := store.ListDeliveries(ctx, "wh_2"); err != nil || len(other) != 1 {
+		t.Fatalf("ListDeliveries wh_2 = %d records, err = %v; want 1", len(other), err)
+	}
+}
+
+func testEnqueueJobs(t *testing.T, store webhook.Store) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	mustCreateWebhooks(t, store, newWebhook("wh_1", "alice"), newWebhook("wh_2", "alice"))
+
+	err := store.EnqueueJobs(ctx, []webhook.Job{
+		newJob("j1", "wh_1", "ev_1", now),
+		newJob("j2", "wh_2", "ev_1", now),
+		newJob("j3", "wh_missing", "ev_1", now),
+	})
+	if err != nil {
+		t.Fatalf("EnqueueJobs: %v", err)
+	}
+	// То же событие на тот же вебхук уже в очереди: outbox мог передать его повторно
+	if err := store.EnqueueJobs(ctx, []webhook.Job{newJob("j4", "wh_1", "ev_1", now)}); err != nil {
+		t.Fatalf("EnqueueJobs repeated event: %v", err)
+	}
+
+	jobs, err := store.ClaimJobs(ctx, now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimJobs: %v", err)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	if len(jobs) != 2 || jobs[0].ID != "j1" || jobs[1].ID != "j2" {
+		t.Fatalf("ClaimJobs = %+v, want j1 and j2", jobs)
+	}
+	want := newJob("j1", "wh_1", "ev_1", now)
+	got := jobs[0]
+	if !got.NextAt.Equal(want.NextAt.Add(time.Minute)) {
+		t.Errorf("claimed NextAt = %v, want %v", got.NextAt, want.NextAt.Add(time.Minute))
+	}
+	got.NextAt = want.NextAt
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("claimed job = %+v, want %+v", got, want)
+	}
+}
+
+func testClaimJobs(t *testing.T, store webhook.Store) {
+	ctx := context.Background()
+	now := time.Now()
+	mustCreateWebhooks(t, store, newWebhook("wh_1", "alice"))
+	err := store.EnqueueJobs(ctx, []webhook.Job{
+		newJob("j1", "wh_1", "ev_1", now.Add(-time.Second)),
+		newJob("j2", "wh_1", "ev_2", now),
+		newJob("j3", "wh_1", "ev_3", now.Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("EnqueueJobs: %v", err)
+	}
+
+	// Сначала самые давние; не наступившие не выдаются
+	if got := claimIDs(t, store, now, 1); !reflect.DeepEqual(got, []string{"j1"}) {
+		t.Fatalf("first claim = %v, want [j1]", got)
+	}
+	if got := claimIDs(t, store, now, 10); !reflect.DeepEqual(got, []string{"j2"}) {
+		t.Fatalf("second claim = %v, want [j2]", got)
+	}
+	// Забранные доставки возвращаются в очередь по истечении аренды
+	if got := claimIDs(t, store, now.Add(30*time.Second), 10); len(got) != 0 {
+		t.Fatalf("claim during lease = %v, want none", got)
+	}
+	if got := claimIDs(t, store, now.Add(2*time.Minute), 10); !reflect.DeepEqual(got, []string{"j1", "j2"}) {
+		t.Fatalf("claim after lease = %v, want [j1 j2]", got)
+	}
+
+	// Удаление вебхука убирает его доставки
+	if err := store.DeleteWebhook(ctx, "wh_1"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if got := claimIDs(t, store, now.Add(24*time.Hour), 10); len(got) != 0 {
+		t.Fatalf("claim after webhook deleted = %v, want none", got)
+	}
+}
+
+func testRetryAndDeleteJob(t *testing.T, store webhook.Store) {
+	ctx := context.Background()
+	now := time.Now()
+	mustCreateWebhooks(t, store, newWebhook("wh_1", "alice"))
+	err := store.EnqueueJobs(ctx, []webhook.Job{newJob("j1", "wh_1", "ev_1", now), newJob("j2", "wh_1", "ev_2", now)})
+	if err != nil {
+		t.Fatalf("EnqueueJobs: %v", err)
+	}
+	claimIDs(t, store, now, 10)
+
+	next := now.Add(5 * time.Second)
+	if err := store.RetryJob(ctx, "j1", 2, next); err != nil {
+		t.Fatalf("RetryJob: %v", err)
+	}
+	if err := store.DeleteJob(ctx, "j2"); err != nil {
+		t.Fatalf("DeleteJob: %v", err)
+	}
+	// Удалённая доставка не возвращается в очередь
+	if err := store.RetryJob(ctx, "j2", 2, next); err != nil {
+		t.Fatalf("RetryJob of deleted job: %v", err)
+	}
+	if err := store.DeleteJob(ctx, "j2"); err != nil {
+		t.Fatalf("second DeleteJob: %v", err)
+	}
+
+	if got := claimIDs(t, store, next.Add(-time.Millisecond), 10); len(got) != 0 {
+		t.Fatalf("claim before retry time = %v, want none", got)
+	}
+	jobs, err := store.ClaimJobs(ctx, next, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimJobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != "j1" || jobs[0].Attempt != 2 {
+		t.Fatalf("ClaimJobs after retry = %+v, want j1 with attempt 2", jobs)
+	}
+}
@@ -0,0 +1,162 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// ErrCorrupt — журнал или снимок повреждены не только в хвосте и не могут быть восстановлены
+var ErrCorrupt = errors.New("task journal is corrupt")
+
+// errPayload — кадр цел (контрольная сумма сошлась), но его содержимое не разбирается.
+// Такую запись процесс дописал полностью, поэтому она не может быть оборванным хвостом.
+var errPayload = errors.New("undecodable record")
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+	opBatch  = "batch"
+	opEvent  = "event"
+	opAck    = "ack"
+)
+
+// record — одна запись журнала. put хранит задачу целиком, поэтому повторное
+// применение записи поверх снимка не меняет результат. batch объединяет записи
+// транзакции, чтобы при сбое они отбрасывались только вместе. event и ack
+// добавляют событие в outbox и удаляют опубликованные события по ключам.
+type record struct {
+	Op    string       `json:"op"`
+	ID    string       `json:"id,omitempty"`
+	Task  *storedTask  `json:"task,omitempty"`
+	Batch []record     `json:"batch,omitempty"`
+	Event *storedEvent `json:"event,omitempty"`
+	Keys  []string     `json:"keys,omitempty"`
+}
+
+// snapshot — состояние хранилища на момент сжатия журнала
+type snapshot struct {
+	Tasks  []storedTask  `json:"tasks"`
+	Outbox []storedEvent `json:"outbox,omitempty"`
+}
+
+// storedTask — задача в формате журнала; в отличие от API сохраняет служебные метки времени
+type storedTask struct {
+	ID          string                        `json:"id"`
+	Owner       string                        `json:"owner"`
+	Title       string                        `json:"title"`
+	Description string                        `json:"description"`
+	DueDate     service.DueDate               `json:"due_date"`
+	Done        bool                          `json:"done"`
+	Shares      map[string]service.Permission `json:"shares,omitempty"`
+	Tags        []string                      `json:"tags,omitempty"`
+	Version     int64                         `json:"version"`
+	CreatedAt   time.Time                     `json:"created_at"`
+	UpdatedAt   time.Time                     `json:"updated_at"`
+}
+
+func fromTask(t service.Task) *storedTask {
+	return &storedTask{
+		ID:          t.ID,
+		Owner:       t.Owner,
+		Title:       t.Title,
+		Description: t.Description,
+		DueDate:     t.DueDate,
+		Done:        t.Done,
+		Shares:      t.Shares,
+		Tags:        t.Tags,
+		Version:     t.Version,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+func (t storedTask) toTask() service.Task {
+	return service.Task{
+		ID:          t.ID,
+		Owner:       t.Owner,
+		Title:       t.Title,
+		Description: t.Description,
+		DueDate:     t.DueDate,
+		Done:        t.Done,
+		Shares:      t.Shares,
+		Tags:        t.Tags,
+		Version:     t.Version,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+// storedEvent — событие outbox в формате журнала
+type storedEvent struct {
+	Key        string            `json:"key"`
+	Type       service.EventType `json:"type"`
+	Task       storedTask        `json:"task"`
+	Before     *storedTask       `json:"before,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+func fromEvent(ev service.OutboxEvent) *storedEvent {
+	stored := &storedEvent{
+		Key:        ev.Key,
+		Type:       ev.Type,
+		Task:       *fromTask(ev.Task),
+		OccurredAt: ev.OccurredAt,
+	}
+	if ev.Before != nil {
+		stored.Before = fromTask(*ev.Before)
+	}
+	return stored
+}
+
+func (e storedEvent) toEvent() service.OutboxEvent {
+	ev := service.OutboxEvent{
+		Key:        e.Key,
+		Type:       e.Type,
+		Task:       e.Task.toTask(),
+		OccurredAt: e.OccurredAt,
+	}
+	if e.Before != nil {
+		before := e.Before.toTask()
+		ev.Before = &before
+	}
+	return ev
+}
+
+// encodeFrame кодирует значение в строку "<crc32 в hex> <json>\n"
+func encodeFrame(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 0, len(data)+10)
+	frame = fmt.Appendf(frame, "%08x ", crc32.ChecksumIEEE(data))
+	frame = append(frame, data...)
+	return append(frame, '\n'), nil
+}
+
+// decodeFrame проверяет контрольную сумму строки и разбирает JSON в v
+func decodeFrame(line []byte, v any) error {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	if len(line) < 10 || line[8] != ' ' {
+		return errors.New("malformed frame")
+	}
+	sum, err := strconv.ParseUint(string(line[:8]), 16, 32)
+	if err != nil {
+		return errors.New("malformed checksum")
+	}
+	data := line[9:]
+	if crc32.ChecksumIEEE(data) != uint32(sum) {
+		return errors.New("checksum mismatch")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", errPayload, err)
+	}
+	return nil
+}
@@ -0,0 +1,530 @@
+package wal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+const (
+	walFile      = "tasks.wal"
+	snapshotFile = "tasks.snapshot"
+)
+
+// Recovery описывает, что было восстановлено при открытии хранилища
+type Recovery struct {
+	SnapshotTasks int
+	Replayed      int
+	// TruncatedBytes — размер недописанного хвоста журнала, отброшенного при старте
+	TruncatedBytes int64
+}
+
+// TaskRepository — хранилище задач в памяти, устойчивое к перезапускам.
+// Каждое изменение дописывается в журнал (WAL) и сбрасывается на диск до того,
+// как станет видно читателям; Compact сворачивает журнал в снимок.
+type TaskRepository struct {
+	// mu сериализует запись в журнал, применение изменений и сжатие
+	mu  sync.Mutex
+	dir string
+	mem *service.MemoryTaskRepository
+	wal journal
+	// size — длина журнала после последней успешной записи
+	size    int64
+	pending int
+}
+
+// journal — файл журнала; в тестах подменяется, чтобы имитировать сбой записи
+type journal interface {
+	io.Writer
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// Open загружает снимок и проигрывает журнал из каталога dir.
+// Недописанная последняя запись (сбой во время записи) отбрасывается,
+// повреждение в середине журнала или в снимке, а также целая по контрольной
+// сумме, но неразборчивая запись возвращают ErrCorrupt.
+func Open(dir string) (*TaskRepository, Recovery, error) {
+	var rec Recovery
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, rec, fmt.Errorf("create journal dir: %w", err)
+	}
+
+	r := &TaskRepository{dir: dir, mem: service.NewMemoryTaskRepository()}
+	n, err := r.loadSnapshot()
+	if err != nil {
+		return nil, rec, err
+	}
+	rec.SnapshotTasks = n
+
+	rec.Replayed, rec.TruncatedBytes, err = r.replay()
+	if err != nil {
+		return nil, rec, err
+	}
+	r.pending = rec.Replayed
+
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, rec, fmt.Errorf("open journal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, rec, fmt.Errorf("stat journal: %w", err)
+	}
+	r.wal, r.size = f, info.Size()
+	return r, rec, nil
+}
+
+func (r *TaskRepository) loadSnapshot() (int, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := decodeFrame(data, &snap); err != nil {
+		return 0, fmt.Errorf("%w: snapshot: %v", ErrCorrupt, err)
+	}
+	for _, t := range snap.Tasks {
+		r.mem.Put(t.toTask())
+	}
+	for _, ev := range snap.Outbox {
+		r.mem.AppendEvent(context.Background(), ev.toEvent())
+	}
+	return len(snap.Tasks), nil
+}
+
+// replay применяет записи журнала поверх снимка и возвращает число применённых
+// записей и размер отброшенного хвоста
+func (r *TaskRepository) replay() (int, int64, error) {
+	path := filepath.Join(r.dir, walFile)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("open journal: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	applied := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return applied, 0, nil
+		}
+		if err != nil && err != io.EOF {
+			return applied, 0, fmt.Errorf("read journal: %w", err)
+		}
+
+		var rec record
+		decodeErr := decodeFrame(line, &rec)
+		if decodeErr == nil && line[len(line)-1] != '\n' {
+			decodeErr = errors.New("record is not terminated")
+		}
+		if decodeErr != nil {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF && !errors.Is(decodeErr, errPayload) {
+				// Хвост журнала не дописан — отбрасываем его
+				info, err := f.Stat()
+				if err != nil {
+					return applied, 0, err
+				}
+				if err := os.Truncate(path, offset); err != nil {
+					return applied, 0, fmt.Errorf("truncate journal: %w", err)
+				}
+				return applied, info.Size() - offset, nil
+			}
+			return applied, 0, fmt.Errorf("%w: record %d at offset %d: %v", ErrCorrupt, applied+1, offset, decodeErr)
+		}
+
+		if err := r.apply(rec); err != nil {
+			return applied, 0, fmt.Errorf("%w: record %d at offset %d: %v", ErrCorrupt, applied+1, offset, err)
+		}
+		applied++
+		offset += int64(len(line))
+	}
+}
+
+func (r *TaskRepository) apply(rec record) error {
+	ctx := context.Background()
+	switch rec.Op {
+	case opPut:
+		if rec.Task == nil {
+			return errors.New("put without task")
+		}
+		r.mem.Put(rec.Task.toTask())
+		return nil
+	case opDelete:
+		if err := r.mem.Delete(ctx, rec.ID); err != nil && !errors.Is(err, service.ErrTaskNotFound) {
+			return err
+		}
+		return nil
+	case opEvent:
+		if rec.Event == nil {
+			return errors.New("event without payload")
+		}
+		return r.mem.AppendEvent(ctx, rec.Event.toEvent())
+	case opAck:
+		return r.mem.AckEvents(ctx, rec.Keys)
+	case opBatch:
+		for _, sub := range rec.Batch {
+			if sub.Op == opBatch {
+				return errors.New("nested batch")
+			}
+			if err := r.apply(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+}
+
+// append дописывает запись в журнал и дожидается fsync; вызывается под r.mu.
+// При ошибке журнал обрезается до прежней длины: иначе недописанный кадр
+// оказался бы в середине журнала перед следующими записями, а запись,
+// о сбое которой узнал вызывающий, могла бы применяться после перезапуска.
+func (r *TaskRepository) append(rec record) error {
+	frame, err := encodeFrame(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := r.wal.Write(frame); err != nil {
+		return r.rollback(fmt.Errorf("write journal: %w", err))
+	}
+	if err := r.wal.Sync(); err != nil {
+		return r.rollback(fmt.Errorf("sync journal: %w", err))
+	}
+	r.size += int64(len(frame))
+	r.pending++
+	return nil
+}
+
+// rollback отбрасывает из журнала всё, что записано после r.size
+func (r *TaskRepository) rollback(cause error) error {
+	if err := r.wal.Truncate(r.size); err != nil {
+		return errors.Join(cause, fmt.Errorf("truncate journal: %w", err))
+	}
+	return cause
+}
+
+func (r *TaskRepository) Create(ctx context.Context, task service.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.mem.Get(ctx, task.ID); err == nil {
+		return service.ErrTaskExists
+	}
+	if err := r.append(record{Op: opPut, ID: task.ID, Task: fromTask(task)}); err != nil {
+		return err
+	}
+	return r.mem.Create(ctx, task)
+}
+
+func (r *TaskRepository) Get(ctx context.Context, id string) (service.Task, error) {
+	return r.mem.Get(ctx, id)
+}
+
+func (r *TaskRepository) ListVisible(ctx context.Context, subject string) ([]service.Task, error) {
+	return r.mem.ListVisible(ctx, subject)
+}
+
+func (r *TaskRepository) Update(ctx context.Context, task service.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, err := r.mem.Get(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	if stored.Version != task.Version-1 {
+		return service.ErrVersionConflict
+	}
+	if err := r.append(record{Op: opPut, ID: task.ID, Task: fromTask(task)}); err != nil {
+		return err
+	}
+	return r.mem.Update(ctx, task)
+}
+
+func (r *TaskRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.mem.Get(ctx, id); err != nil {
+		return err
+	}
+	if err := r.append(record{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	return r.mem.Delete(ctx, id)
+}
+
+func (r *TaskRepository) AppendEvent(ctx context.Context, ev service.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.append(record{Op: opEvent, Event: fromEvent(ev)}); err != nil {
+		return err
+	}
+	return r.mem.AppendEvent(ctx, ev)
+}
+
+func (r *TaskRepository) PendingEvents(ctx context.Context, limit int) ([]service.OutboxEvent, error) {
+	return r.mem.PendingEvents(ctx, limit)
+}
+
+func (r *TaskRepository) AckEvents(ctx context.Context, keys []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.append(record{Op: opAck, Keys: keys}); err != nil {
+		return err
+	}
+	return r.mem.AckEvents(ctx, keys)
+}
+
+// InTx выполняет fn поверх текущих задач и записывает все сделанные изменения
+// в журнал одной записью: при сбое во время записи теряется весь пакет, а не его часть.
+// Изменения попадают в хранилище только после записи пакета, поэтому читатели
+// не видят незафиксированного состояния, а транзакция не копирует все задачи.
+func (r *TaskRepository) InTx(ctx context.Context, fn func(tx service.TaskRepository) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tx := &txRepository{base: r.mem, tasks: make(map[string]*service.Task)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.records) == 0 {
+		return nil
+	}
+	batch := record{Op: opBatch, Batch: tx.records}
+	if err := r.append(batch); err != nil {
+		return err
+	}
+	return r.apply(batch)
+}
+
+// txRepository — репозиторий внутри InTx: читает задачи хранилища, изменения
+// держит поверх них и копит записи для журнала. base не меняется, пока идёт
+// транзакция: все записи в него идут под r.mu.
+type txRepository struct {
+	base *service.MemoryTaskRepository
+	// tasks — задачи, изменённые в транзакции; nil означает удалённую задачу
+	tasks   map[string]*service.Task
+	events  []service.OutboxEvent
+	acked   map[string]bool
+	records []record
+}
+
+func (t *txRepository) Create(ctx context.Context, task service.Task) error {
+	if _, err := t.Get(ctx, task.ID); err == nil {
+		return service.ErrTaskExists
+	}
+	t.put(task)
+	t.records = append(t.records, record{Op: opPut, ID: task.ID, Task: fromTask(task)})
+	return nil
+}
+
+func (t *txRepository) Get(ctx context.Context, id string) (service.Task, error) {
+	if task, ok := t.tasks[id]; ok {
+		if task == nil {
+			return service.Task{}, service.ErrTaskNotFound
+		}
+		return *task, nil
+	}
+	return t.base.Get(ctx, id)
+}
+
+func (t *txRepository) ListVisible(ctx context.Context, subject string) ([]service.Task, error) {
+	tasks, err := t.base.ListVisible(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	tasks = slices.DeleteFunc(tasks, func(task service.Task) bool {
+		_, changed := t.tasks[task.ID]
+		return changed
+	})
+	for _, task := range t.tasks {
+		if task != nil && task.CanView(subject) {
+			tasks = append(tasks, *task)
+		}
+	}
+	return tasks, nil
+}
+
+func (t *txRepository) Update(ctx context.Context, task service.Task) error {
+	stored, err := t.Get(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	if stored.Version != task.Version-1 {
+		return service.ErrVersionConflict
+	}
+	t.put(task)
+	t.records = append(t.records, record{Op: opPut, ID: task.ID, Task: fromTask(task)})
+	return nil
+}
+
+func (t *txRepository) Delete(ctx context.Context, id string) error {
+	if _, err := t.Get(ctx, id); err != nil {
+		return err
+	}
+	t.tasks[id] = nil
+	t.records = append(t.records, record{Op: opDelete, ID: id})
+	return nil
+}
+
+func (t *txRepository) put(task service.Task) {
+	t.tasks[task.ID] = &task
+}
+
+func (t *txRepository) AppendEvent(ctx context.Context, ev service.OutboxEvent) error {
+	pending, err := t.PendingEvents(ctx, math.MaxInt)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(pending, func(e service.OutboxEvent) bool { return e.Key == ev.Key }) {
+		return nil
+	}
+	t.events = append(t.events, ev)
+	t.records = append(t.records, record{Op: opEvent, Event: fromEvent(ev)})
+	return nil
+}
+
+func (t *txRepository) PendingEvents(ctx context.Context, limit int) ([]service.OutboxEvent, error) {
+	pending, err := t.base.PendingEvents(ctx, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	pending = append(pending, t.events...)
+	pending = slices.DeleteFunc(pending, func(e service.OutboxEvent) bool { return t.acked[e.Key] })
+	return pending[:min(limit, len(pending))], nil
+}
+
+func (t *txRepository) AckEvents(ctx context.Context, keys []string) error {
+	if t.acked == nil {
+		t.acked = make(map[string]bool, len(keys))
+	}
+	for _, key := range keys {
+		t.acked[key] = true
+	}
+	t.events = slices.DeleteFunc(t.events, func(e service.OutboxEvent) bool { return t.acked[e.Key] })
+	t.records = append(t.records, record{Op: opAck, Keys: keys})
+	return nil
+}
+
+func (t *txRepository) InTx(ctx context.Context, fn func(tx service.TaskRepository) error) error {
+	return fn(t)
+}
+
+// Compact записывает снимок текущего состояния и очищает журнал.
+// Снимок заменяется атомарно; если процесс упадёт до очистки журнала,
+// его записи при следующем старте повторно применятся к снимку без последствий.
+// Возвращает число свёрнутых записей журнала.
+func (r *TaskRepository) Compact() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == 0 {
+		return 0, nil
+	}
+
+	tasks := r.mem.All()
+	snap := snapshot{Tasks: make([]storedTask, 0, len(tasks))}
+	for _, t := range tasks {
+		snap.Tasks = append(snap.Tasks, *fromTask(t))
+	}
+	for _, ev := range r.mem.Outbox() {
+		snap.Outbox = append(snap.Outbox, *fromEvent(ev))
+	}
+	frame, err := encodeFrame(snap)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeFileSync(filepath.Join(r.dir, snapshotFile), frame); err != nil {
+		return 0, fmt.Errorf("write snapshot: %w", err)
+	}
+
+	if err := r.wal.Truncate(0); err != nil {
+		return 0, fmt.Errorf("truncate journal: %w", err)
+	}
+	r.size = 0
+	if err := r.wal.Sync(); err != nil {
+		return 0, fmt.Errorf("sync journal: %w", err)
+	}
+	n := r.pending
+	r.pending = 0
+	return n, nil
+}
+
+// Run периодически сжимает журнал, пока не отменён ctx
+func (r *TaskRepository) Run(ctx context.Context, interval time.Duration, onCompact func(n int, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := r.Compact()
+			if onCompact != nil && (n > 0 || err != nil) {
+				onCompact(n, err)
+			}
+		}
+	}
+}
+
+// Close сжимает журнал и закрывает файл
+func (r *TaskRepository) Close() error {
+	_, err := r.Compact()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cerr := r.wal.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeFileSync атомарно заменяет файл: пишет во временный, делает fsync и переименовывает
+func writeFileSync(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// fsync каталога фиксирует само переименование
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -0,0 +1,459 @@
+package wal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/storagetest"
+)
+
+func TestTaskRepository(t *testing.T) {
+	storagetest.RunTaskRepositoryTests(t, func(t *testing.T) service.TaskRepository {
+		repo, _, err := Open(t.TempDir())
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+// crash закрывает файл журнала без сжатия, как при аварийном завершении процесса
+func crash(t *testing.T, r *TaskRepository) {
+	t.Helper()
+	if err := r.wal.Close(); err != nil {
+		t.Fatalf("close journal: %v", err)
+	}
+}
+
+func mustOpen(t *testing.T, dir string) (*TaskRepository, Recovery) {
+	t.Helper()
+	r, rec, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { r.wal.Close() })
+	return r, rec
+}
+
+func taskIDs(t *testing.T, r *TaskRepository, subject string) []string {
+	t.Helper()
+	tasks, err := r.ListVisible(context.Background(), subject)
+	if err != nil {
+		t.Fatalf("ListVisible: %v", err)
+	}
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestReplayDiscardsTornTail(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// tear портит конец журнала так, как его оставил бы сбой во время записи
+		tear func(t *testing.T, path string)
+		want []string
+	}{
+		{
+			name: "half-written record",
+			tear: func(t *testing.T, path string) {
+				frame, err := encodeFrame(record{Op: opPut, Task: fromTask(storagetest.NewTask("t3", "alice"))})
+				if err != nil {
+					t.Fatal(err)
+				}
+				appendBytes(t, path, frame[:len(frame)/2])
+			},
+			want: []string{"t1", "t2"},
+		},
+		{
+			name: "record without newline",
+			tear: func(t *testing.T, path string) {
+				frame, err := encodeFrame(record{Op: opPut, Task: fromTask(storagetest.NewTask("t3", "alice"))})
+				if err != nil {
+					t.Fatal(err)
+				}
+				appendBytes(t, path, frame[:len(frame)-1])
+			},
+			want: []string{"t1", "t2"},
+		},
+		{
+			// Транзакция записана одной строкой и отбрасывается целиком
+			name: "torn transaction",
+			tear: func(t *testing.T, path string) {
+				if err := os.Truncate(path, fileSize(t, path)-3); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"t1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, walFile)
+			r, _ := mustOpen(t, dir)
+			storagetest.MustCreate(t, r, storagetest.NewTask("t1", "alice"))
+			err := r.InTx(ctx, func(tx service.TaskRepository) error {
+				if err := tx.Create(ctx, storagetest.NewTask("t2", "alice")); err != nil {
+					return err
+				}
+				return tx.AppendEvent(ctx, service.OutboxEvent{Key: "ev_t2", Type: service.EventCreated, Task: storagetest.NewTask("t2", "alice")})
+			})
+			if err != nil {
+				t.Fatalf("InTx: %v", err)
+			}
+			crash(t, r)
+			intact := fileSize(t, path)
+			tt.tear(t, path)
+			torn := fileSize(t, path)
+
+			r, rec := mustOpen(t, dir)
+			if got := taskIDs(t, r, "alice"); !slices.Equal(got, tt.want) {
+				t.Fatalf("tasks after recovery = %v, want %v", got, tt.want)
+			}
+			if rec.TruncatedBytes == 0 || rec.Replayed != len(tt.want) {
+				t.Fatalf("recovery = %+v, want %d records replayed and a discarded tail", rec, len(tt.want))
+			}
+			if size := fileSize(t, path); size != torn-rec.TruncatedBytes || size > intact {
+				t.Fatalf("journal is %d bytes after recovery, torn %d, discarded %d", size, torn, rec.TruncatedBytes)
+			}
+
+			// Журнал после обрезки снова пригоден для записи
+			storagetest.MustCreate(t, r, storagetest.NewTask("t4", "alice"))
+			crash(t, r)
+			r, rec = mustOpen(t, dir)
+			if got, want := taskIDs(t, r, "alice"), append(slices.Clone(tt.want), "t4"); !slices.Equal(got, want) {
+				t.Fatalf("tasks after second recovery = %v, want %v", got, want)
+			}
+			if rec.TruncatedBytes != 0 {
+				t.Fatalf("second recovery discarded %d bytes", rec.TruncatedBytes)
+			}
+		})
+	}
+}
+
+func TestReplayRefusesCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, walFile)
+	r, _ := mustOpen(t, dir)
+	storagetest.MustCreate(t, r,
+		storagetest.NewTask("t1", "alice"),
+		storagetest.NewTask("t2", "alice"),
+		storagetest.NewTask("t3", "alice"))
+	crash(t, r)
+
+	// Меняем заголовок второй записи: контрольная сумма перестаёт сходиться
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	lines[1] = bytes.Replace(lines[1], []byte(`"t2"`), []byte(`"tX"`), 1)
+	corrupted := bytes.Join(lines, nil)
+	if err := os.WriteFile(path, corrupted, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Open(dir); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Open: err = %v, want %v", err, ErrCorrupt)
+	}
+	// Повреждённый журнал не обрезается: записи после него остаются для ручного разбора
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, corrupted) {
+		t.Fatal("corrupt journal was modified")
+	}
+}
+
+func TestReplayKeepsLegacyDueDate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, walFile)
+	r, _ := mustOpen(t, dir)
+	storagetest.MustCreate(t, r, storagetest.NewTask("t1", "alice"))
+	crash(t, r)
+
+	// Последняя запись — задача из старой версии со сроком в свободной форме
+	frame, err := encodeFrame(map[string]any{
+		"op": opPut, "id": "t2",
+		"task": map[string]any{"id": "t2", "owner": "alice", "title": "legacy", "due_date": "next friday", "version": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendBytes(t, path, frame)
+
+	r, rec := mustOpen(t, dir)
+	if rec.Replayed != 2 || rec.TruncatedBytes != 0 {
+		t.Fatalf("recovery = %+v, want 2 replayed records and no discarded tail", rec)
+	}
+	task, err := r.Get(context.Background(), "t2")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := task.DueDate.String(); got != "next friday" {
+		t.Fatalf("due_date = %q, want %q", got, "next friday")
+	}
+}
+
+func TestReplayRefusesUndecodableTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, walFile)
+	r, _ := mustOpen(t, dir)
+	storagetest.MustCreate(t, r, storagetest.NewTask("t1", "alice"))
+	crash(t, r)
+
+	// Кадр цел, но его содержимое не разбирается: это не оборванная запись,
+	// и отбрасывать её молча нельзя
+	frame, err := encodeFrame(map[string]any{"op": opPut, "id": "t2", "task": map[string]any{"id": "t2", "version": "one"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendBytes(t, path, frame)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Open(dir); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Open: err = %v, want %v", err, ErrCorrupt)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Fatal("journal with an intact record was truncated")
+	}
+}
+
+func TestReplayRefusesCorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	r, _ := mustOpen(t, dir)
+	storagetest.MustCreate(t, r, storagetest.NewTask("t1", "alice"))
+	if _, err := r.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	crash(t, r)
+
+	path := filepath.Join(dir, snapshotFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(data, []byte(`"t1"`), []byte(`"tX"`), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Open(dir); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Open: err = %v, want %v", err, ErrCorrupt)
+	}
+}
+
+func TestRecoveryAfterCompaction(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	r, _ := mustOpen(t, dir)
+	t1, t2 := storagetest.NewTask("t1", "alice"), storagetest.NewTask("t2", "alice")
+	storagetest.MustCreate(t, r, t1, t2)
+	if err := r.AppendEvent(ctx, service.OutboxEvent{Key: "ev_1", Type: service.EventCreated, Task: t1}); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+	if n, err := r.Compact(); err != nil || n != 3 {
+		t.Fatalf("Compact = %d, %v; want 3 records", n, err)
+	}
+	if size := fileSize(t, filepath.Join(dir, walFile)); size != 0 {
+		t.Fatalf("journal is %d bytes after compaction", size)
+	}
+
+	// Изменения после сжатия попадают только в журнал
+	updated := t1
+	updated.Title = "renamed"
+	updated.Version = 2
+	if err := r.Update(ctx, updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := r.Delete(ctx, "t2"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := r.AppendEvent(ctx, service.OutboxEvent{Key: "ev_2", Type: service.EventUpdated, Task: updated}); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+	if err := r.AckEvents(ctx, []string{"ev_1"}); err != nil {
+		t.Fatalf("AckEvents: %v", err)
+	}
+	journal, err := os.ReadFile(filepath.Join(dir, walFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crash(t, r)
+
+	check := func(t *testing.T, r *TaskRepository) {
+		t.Helper()
+		got, err := r.Get(ctx, "t1")
+		if err != nil || got.Title != "renamed" || got.Version != 2 {
+			t.Fatalf("t1 after recovery: %+v, %v", got, err)
+		}
+		if _, err := r.Get(ctx, "t2"); !errors.Is(err, service.ErrTaskNotFound) {
+			t.Fatalf("deleted t2 after recovery: err = %v", err)
+		}
+		events, err := r.PendingEvents(ctx, 10)
+		if err != nil || len(events) != 1 || events[0].Key != "ev_2" {
+			t.Fatalf("outbox after recovery = %+v, %v; want only ev_2", events, err)
+		}
+	}
+
+	r, rec := mustOpen(t, dir)
+	if rec.SnapshotTasks != 2 || rec.Replayed != 4 || rec.TruncatedBytes != 0 {
+		t.Fatalf("recovery = %+v, want 2 snapshot tasks and 4 replayed records", rec)
+	}
+	check(t, r)
+
+	// Сбой между записью снимка и очисткой журнала: журнал проигрывается
+	// поверх снимка, в который он уже вошёл, и состояние не меняется
+	if _, err := r.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	crash(t, r)
+	if err := os.WriteFile(filepath.Join(dir, walFile), journal, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, rec = mustOpen(t, dir)
+	if rec.SnapshotTasks != 1 || rec.Replayed != 4 {
+		t.Fatalf("recovery = %+v, want 1 snapshot task and 4 replayed records", rec)
+	}
+	check(t, r)
+}
+
+// tornJournal дописывает только половину следующего кадра и возвращает ошибку,
+// как при переполнении диска во время записи
+type tornJournal struct {
+	journal
+	fail bool
+}
+
+func (j *tornJournal) Write(p []byte) (int, error) {
+	if !j.fail {
+		return j.journal.Write(p)
+	}
+	j.fail = false
+	n, _ := j.journal.Write(p[:len(p)/2])
+	return n, errors.New("no space left on device")
+}
+
+func TestFailedAppendLeavesNoPartialRecord(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	r, _ := mustOpen(t, dir)
+	j := &tornJournal{journal: r.wal}
+	r.wal = j
+	storagetest.MustCreate(t, r, storagetest.NewTask("t1", "alice"))
+	size := fileSize(t, filepath.Join(dir, walFile))
+
+	j.fail = true
+	if err := r.Create(ctx, storagetest.NewTask("t2", "alice")); err == nil {
+		t.Fatal("Create succeeded with a failing journal")
+	}
+	if got := fileSize(t, filepath.Join(dir, walFile)); got != size {
+		t.Fatalf("journal is %d bytes after failed write, want %d", got, size)
+	}
+	if _, err := r.Get(ctx, "t2"); !errors.Is(err, service.ErrTaskNotFound) {
+		t.Fatalf("task from failed write is visible: err = %v", err)
+	}
+
+	// Следующая запись ложится сразу за последней целой, и журнал проигрывается без потерь
+	storagetest.MustCreate(t, r, storagetest.NewTask("t3", "alice"))
+	crash(t, r)
+	r, rec := mustOpen(t, dir)
+	if rec.Replayed != 2 || rec.TruncatedBytes != 0 {
+		t.Fatalf("recovery = %+v, want 2 replayed records", rec)
+	}
+	if got := taskIDs(t, r, "alice"); !slices.Equal(got, []string{"t1", "t3"}) {
+		t.Fatalf("tasks after recovery = %v, want [t1 t3]", got)
+	}
+}
+
+func TestInTxAppliesChangesOnCommit(t *testing.T) {
+	ctx := context.Background()
+	r, _ := mustOpen(t, t.TempDir())
+	storagetest.MustCreate(t, r, storagetest.NewTask("t1", "alice"), storagetest.NewTask("t2", "alice"))
+
+	errAbort := errors.New("abort")
+	err := r.InTx(ctx, func(tx service.TaskRepository) error {
+		if err := tx.Delete(ctx, "t1"); err != nil {
+			return err
+		}
+		if err := tx.Create(ctx, storagetest.NewTask("t3", "alice")); err != nil {
+			return err
+		}
+		// Транзакция видит свои изменения, остальные читатели — нет
+		if _, err := r.Get(ctx, "t1"); err != nil {
+			t.Errorf("task deleted by open tx is gone: %v", err)
+		}
+		if _, err := r.Get(ctx, "t3"); !errors.Is(err, service.ErrTaskNotFound) {
+			t.Errorf("task created by open tx is visible: err = %v", err)
+		}
+		tasks, err := tx.ListVisible(ctx, "alice")
+		if err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(tasks))
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		sort.Strings(ids)
+		if !slices.Equal(ids, []string{"t2", "t3"}) {
+			t.Errorf("tx.ListVisible = %v, want [t2 t3]", ids)
+		}
+		if err := tx.Create(ctx, storagetest.NewTask("t3", "alice")); !errors.Is(err, service.ErrTaskExists) {
+			t.Errorf("second Create in tx: err = %v, want %v", err, service.ErrTaskExists)
+		}
+		if _, err := tx.Get(ctx, "t1"); !errors.Is(err, service.ErrTaskNotFound) {
+			t.Errorf("deleted task in tx: err = %v", err)
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("InTx err = %v, want %v", err, errAbort)
+	}
+	if got := taskIDs(t, r, "alice"); !slices.Equal(got, []string{"t1", "t2"}) {
+		t.Fatalf("tasks after rollback = %v, want [t1 t2]", got)
+	}
+
+	err = r.InTx(ctx, func(tx service.TaskRepository) error {
+		if err := tx.Delete(ctx, "t1"); err != nil {
+			return err
+		}
+		return tx.Create(ctx, storagetest.NewTask("t3", "alice"))
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+	if got := taskIDs(t, r, "alice"); !slices.Equal(got, []string{"t2", "t3"}) {
+		t.Fatalf("tasks after commit = %v, want [t2 t3]", got)
+	}
+}
+
+func appendBytes(t *testing.T, path string, data []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
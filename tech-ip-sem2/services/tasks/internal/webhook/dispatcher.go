@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+const (
+	// jobLease — на сколько откладывается выполняемая доставка; больше таймаута запроса
+	jobLease = time.Minute
+	// claimBatch — сколько доставок забирается из очереди за раз
+	claimBatch = 64
+)
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// payload — тело запроса доставки. EventID — ключ дедупликации события:
+// при повторной публикации из outbox он тот же
+type payload struct {
+	EventID    string            `json:"event_id"`
+	Event      service.EventType `json:"event"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Task       service.Task      `json:"task"`
+}
+
+// Sign возвращает подпись доставки: HMAC-SHA256 от "<timestamp>.<body>" в hex
+// с префиксом sha256=. Метка времени в подписи не даёт переотправить старый запрос.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ConsumeEvents ставит в очередь доставки событий на подходящие вебхуки. Это
+// service.EventConsumer: TaskService удаляет события из outbox только после того,
+// как доставки сохранены, поэтому они переживают сбой и перезапуск сервиса.
+func (s *Service) ConsumeEvents(ctx context.Context, events []service.Event) error {
+	hooks, err := s.store.ListWebhooks(ctx, "")
+	if err != nil {
+		return fmt.Errorf("load webhooks: %w", err)
+	}
+	var jobs []Job
+	now := time.Now()
+	for _, ev := range events {
+		for _, hook := range hooks {
+			// Событие отправляется таким, каким его видит владелец вебхука
+			visible, ok := ev.ForSubject(hook.Owner)
+			if !ok || !hook.subscribed(visible.Type) {
+				continue
+			}
+			body, err := json.Marshal(payload{
+				EventID:    visible.Key,
+				Event:      visible.Type,
+				OccurredAt: visible.OccurredAt,
+				Task:       visible.Task,
+			})
+			if err != nil {
+				return fmt.Errorf("encode webhook payload: %w", err)
+			}
+			jobs = append(jobs, Job{
+				ID:        uuid.NewString(),
+				WebhookID: hook.ID,
+				EventKey:  visible.Key,
+				EventType: visible.Type,
+				TaskID:    visible.Task.ID,
+				Payload:   body,
+				NextAt:    now,
+			})
+		}
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+	if err := s.store.EnqueueJobs(ctx, jobs); err != nil {
+		return fmt.Errorf("enqueue webhook deliveries: %w", err)
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run отправляет доставки из очереди, пока не отменён ctx: сразу после постановки
+// новых и раз в interval, чтобы подобрать повторы и доставки, брошенные упавшим
+// экземпляром. Перед возвратом дожидается начатых запросов.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	logEntry := s.logger.WithField("component", "webhook_dispatcher")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+
+	for {
+		jobs, err := s.store.ClaimJobs(ctx, time.Now(), jobLease, claimBatch)
+		if err != nil && ctx.Err() == nil {
+			logEntry.WithError(err).Error("failed to claim webhook deliveries")
+		}
+		for _, job := range jobs {
+			// Не начатые доставки вернутся в очередь по истечении jobLease
+			select {
+			case s.sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			inflight.Add(1)
+			go func() {
+				defer inflight.Done()
+				defer func() { <-s.sem }()
+				s.attempt(ctx, job)
+			}()
+		}
+		if len(jobs) == claimBatch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// attempt делает очередную попытку доставки job и по её итогу удаляет доставку
+// из очереди или назначает повтор по политике s.policy. Повторяются сетевые ошибки,
+// 5xx, 408 и 429; остальные ответы и запрещённый Guard адрес считаются окончательными.
+func (s *Service) attempt(ctx context.Context, job Job) {
+	logEntry := s.logger.WithFields(logrus.Fields{
+		"component":   "webhook_dispatcher",
+		"webhook_id":  job.WebhookID,
+		"delivery_id": job.ID,
+		"event_key":   job.EventKey,
+	})
+
+	// Вебхук могли удалить или заменить секрет, пока шли повторы
+	hook, err := s.store.GetWebhook(ctx, job.WebhookID)
+	if errors.Is(err, ErrNotFound) {
+		logEntry.Debug("webhook deleted, delivery stopped")
+		s.finish(ctx, logEntry, job)
+		return
+	}
+	if err != nil {
+		logEntry.WithError(err).Error("failed to load webhook, delivery postponed")
+		return
+	}
+
+	d := Delivery{ID: job.ID, EventKey: job.EventKey, EventType: job.EventType, TaskID: job.TaskID, Attempt: job.Attempt + 1, At: time.Now()}
+	d.StatusCode, err = s.send(ctx, hook, job)
+	if ctx.Err() != nil {
+		// Сервис останавливается: попытка не считается, доставка вернётся в очередь
+		return
+	}
+	d.Duration = time.Since(d.At)
+	d.Success = err == nil
+	if err != nil {
+		d.Error = err.Error()
+	}
+	if err := s.store.AddDelivery(ctx, hook.ID, d, maxDeliveryLog); err != nil {
+		logEntry.WithError(err).Error("failed to record webhook delivery")
+	}
+
+	entry := logEntry.WithFields(logrus.Fields{
+		"attempt": d.Attempt,
+		"status":  d.StatusCode,
+	})
+	switch {
+	case d.Success:
+		entry.Info("webhook delivered")
+		s.finish(ctx, logEntry, job)
+	case !retryable(d.StatusCode, err) || d.Attempt >= s.policy.MaxAttempts:
+		entry.WithField("error", d.Error).Warn("webhook delivery failed")
+		s.finish(ctx, logEntry, job)
+	default:
+		entry.WithField("error", d.Error).Debug("webhook delivery attempt failed, retrying")
+		if err := s.store.RetryJob(ctx, job.ID, d.Attempt, time.Now().Add(s.policy.delay(d.Attempt))); err != nil {
+			logEntry.WithError(err).Error("failed to schedule webhook delivery retry")
+		}
+	}
+}
+
+// finish убирает доставку из очереди; если это не удалось, она будет отправлена ещё раз
+func (s *Service) finish(ctx context.Context, logEntry *logrus.Entry, job Job) {
+	if err := s.store.DeleteJob(ctx, job.ID); err != nil {
+		logEntry.WithError(err).Error("failed to remove webhook delivery from queue")
+	}
+}
+
+func (s *Service) send(ctx context.Context, hook Webhook, job Job) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(job.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tasks-webhooks/1")
+	req.Header.Set(HeaderEvent, string(job.EventType))
+	req.Header.Set(HeaderDelivery, job.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, job.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func retryable(status int, err error) bool {
+	switch {
+	case status == 0:
+		return err != nil && !errors.Is(err, ErrForbiddenAddress)
+	case status >= 500, status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	default:
+		return false
+	}
+}
+
+// delay — задержка перед попыткой attempt+1: BaseDelay·2^(attempt-1), не больше MaxDelay,
+// со случайным разбросом в половину задержки, чтобы повторы не шли одновременно
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
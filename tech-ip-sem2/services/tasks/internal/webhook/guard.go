@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress — адрес получателя ведёт во внутреннюю или служебную сеть
+var ErrForbiddenAddress = errors.New("address is not allowed for webhooks")
+
+// blockedPrefixes — служебные диапазоны, которые не отсекаются методами netip.Addr
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// CGNAT: адреса внутри сети провайдера или облака
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	// NAT64 и 6to4 содержат внутри IPv4-адрес, который может оказаться внутренним
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// Guard не пускает доставки во внутренние сети сервиса (SSRF). Адрес проверяется
+// при регистрации вебхука и ещё раз при каждом соединении — для того IP, к которому
+// идёт подключение после разрешения имени, поэтому смена DNS-записи после регистрации
+// ничего не даёт.
+type Guard struct {
+	// AllowPrivate отключает проверки; только для локальной разработки и тестов
+	AllowPrivate bool
+	// Resolver разрешает имена при регистрации; nil — net.DefaultResolver
+	Resolver *net.Resolver
+}
+
+// allowed сообщает, можно ли отправлять доставки на ip
+func (g Guard) allowed(ip netip.Addr) bool {
+	if g.AllowPrivate {
+		return true
+	}
+	ip = ip.Unmap()
+	// IsGlobalUnicast отсекает loopback, link-local, multicast, unspecified и broadcast
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkURL разрешает имя хоста u и проверяет все его адреса
+func (g Guard) checkURL(ctx context.Context, u *url.URL) error {
+	if g.AllowPrivate {
+		return nil
+	}
+	host := u.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !g.allowed(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+		}
+		return nil
+	}
+
+	resolver := g.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !g.allowed(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, host, ip)
+		}
+	}
+	return nil
+}
+
+// control проверяет адрес, к которому подключается net.Dialer, уже после разрешения имени
+func (g Guard) control(network, address string, _ syscall.RawConn) error {
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+	if !g.allowed(addr.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr.Addr())
+	}
+	return nil
+}
+
+// Transport возвращает транспорт для доставок, соединения которого проходят проверку
+// Guard. Прокси из окружения не используется: через него адрес получателя не проверить.
+func (g Guard) Transport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second, Control: g.control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
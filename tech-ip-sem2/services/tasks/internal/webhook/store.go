@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+// Store — хранилище вебхуков, журнала их доставок и очереди доставок
+type Store interface {
+	// CreateWebhook сохраняет новый вебхук
+	CreateWebhook(ctx context.Context, hook Webhook) error
+	// GetWebhook возвращает ErrNotFound, если вебхука нет
+	GetWebhook(ctx context.Context, id string) (Webhook, error)
+	// ListWebhooks возвращает вебхуки владельца в порядке создания; пустой owner — все вебхуки
+	ListWebhooks(ctx context.Context, owner string) ([]Webhook, error)
+	// DeleteWebhook удаляет вебхук вместе с журналом и очередью; ErrNotFound, если вебхука нет
+	DeleteWebhook(ctx context.Context, id string) error
+	// AddDelivery добавляет попытку в журнал вебхука и оставляет в нём последние keep записей.
+	// Попытки удалённого вебхука не сохраняются.
+	AddDelivery(ctx context.Context, webhookID string, d Delivery, keep int) error
+	// ListDeliveries возвращает журнал вебхука, начиная с последней попытки
+	ListDeliveries(ctx context.Context, webhookID string) ([]Delivery, error)
+
+	// EnqueueJobs ставит доставки в очередь. Доставка того же события на тот же вебхук
+	// (WebhookID, EventKey) повторно не добавляется, доставки удалённых вебхуков пропускаются.
+	EnqueueJobs(ctx context.Context, jobs []Job) error
+	// ClaimJobs забирает до limit доставок, время которых наступило к now, и откладывает
+	// их до now+lease: пока доставка выполняется, другой доставщик её не возьмёт,
+	// а если доставщик упал, она вернётся в очередь по истечении lease
+	ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Job, error)
+	// RetryJob сохраняет число сделанных попыток и время следующей; удалённая доставка пропускается
+	RetryJob(ctx context.Context, id string, attempt int, next time.Time) error
+	// DeleteJob убирает завершённую доставку из очереди
+	DeleteJob(ctx context.Context, id string) error
+}
+
+// Job — доставка события на вебхук, ожидающая отправки
+type Job struct {
+	// ID — id доставки, общий для всех её попыток
+	ID        string
+	WebhookID string
+	EventKey  string
+	EventType service.EventType
+	TaskID    string
+	// Payload — тело запроса: событие в том виде, в каком его видит владелец вебхука
+	Payload []byte
+	// Attempt — сколько попыток уже сделано
+	Attempt int
+	NextAt  time.Time
+}
+
+// MemoryStore хранит вебхуки и очередь доставок в памяти процесса
+type MemoryStore struct {
+	mu    sync.RWMutex
+	hooks map[string]*entry
+	jobs  map[string]*Job
+}
+
+type entry struct {
+	hook Webhook
+	log  []Delivery
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{hooks: make(map[string]*entry), jobs: make(map[string]*Job)}
+}
+
+func (s *MemoryStore) CreateWebhook(ctx context.Context, hook Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks[hook.ID] = &entry{hook: hook}
+	return nil
+}
+
+func (s *MemoryStore) GetWebhook(ctx context.Context, id string) (Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.hooks[id]
+	if !ok {
+		return Webhook{}, ErrNotFound
+	}
+	return e.hook, nil
+}
+
+func (s *MemoryStore) ListWebhooks(ctx context.Context, owner string) ([]Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hooks := make([]Webhook, 0)
+	for _, e := range s.hooks {
+		if owner == "" || e.hook.Owner == owner {
+			hooks = append(hooks, e.hook)
+		}
+	}
+	// id начинается с UUIDv7, поэтому сортировка по нему — это порядок создания
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return hooks, nil
+}
+
+func (s *MemoryStore) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.hooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.hooks, id)
+	for jobID, job := range s.jobs {
+		if job.WebhookID == id {
+			delete(s.jobs, jobID)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) AddDelivery(ctx context.Context, webhookID string, d Delivery, keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.hooks[webhookID]
+	if !ok {
+		return nil
+	}
+	e.log = append(e.log, d)
+	if len(e.log) > keep {
+		e.log = slices.Clone(e.log[len(e.log)-keep:])
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListDeliveries(ctx context.Context, webhookID string) ([]Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.hooks[webhookID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	log := slices.Clone(e.log)
+	slices.Reverse(log)
+	return log, nil
+}
+
+func (s *MemoryStore) EnqueueJobs(ctx context.Context, jobs []Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range jobs {
+		if _, ok := s.hooks[job.WebhookID]; !ok {
+			continue
+		}
+		queued := false
+		for _, j := range s.jobs {
+			if j.WebhookID == job.WebhookID && j.EventKey == job.EventKey {
+				queued = true
+				break
+			}
+		}
+		if !queued {
+			job.Payload = slices.Clone(job.Payload)
+			s.jobs[job.ID] = &job
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := make([]*Job, 0)
+	for _, job := range s.jobs {
+		if !job.NextAt.After(now) {
+			due = append(due, job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAt.Equal(due[j].NextAt) {
+			return due[i].NextAt.Before(due[j].NextAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	claimed := make([]Job, 0, min(limit, len(due)))
+	for _, job := range due[:min(limit, len(due))] {
+		job.NextAt = now.Add(lease)
+		claimed = append(claimed, *job)
+	}
+	return claimed, nil
+}
+
+func (s *MemoryStore) RetryJob(ctx context.Context, id string, attempt int, next time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		job.Attempt = attempt
+		job.NextAt = next
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteJob(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
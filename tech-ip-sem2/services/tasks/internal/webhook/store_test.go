@@ -0,0 +1,14 @@
+package webhook_test
+
+import (
+	"testing"
+
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/storage/storagetest"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/webhook"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storagetest.RunWebhookStoreTests(t, func(t *testing.T) webhook.Store {
+		return webhook.NewMemoryStore()
+	})
+}
@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/sun1tar/MIREA-TIP-Practice-19/tech-ip-sem2/tasks/internal/service"
+)
+
+var (
+	ErrNotFound       = errors.New("webhook not found")
+	ErrInvalidWebhook = errors.New("invalid webhook")
+)
+
+// maxDeliveryLog — сколько последних попыток доставки хранится для каждого вебхука
+const maxDeliveryLog = 100
+
+// Webhook — адрес, на который отправляются события задач владельца
+type Webhook struct {
+	ID     string
+	Owner  string
+	URL    string
+	Events []service.EventType
+	// Secret — ключ подписи HMAC-SHA256 тела запроса. Хранится как есть:
+	// в отличие от пароля, он нужен для подписи каждой доставки.
+	Secret    string
+	CreatedAt time.Time
+}
+
+func (w Webhook) subscribed(t service.EventType) bool {
+	return slices.Contains(w.Events, t)
+}
+
+// Delivery — одна попытка доставки события
+type Delivery struct {
+	// ID общий для всех попыток доставки одного события на один вебхук
+	ID         string
+	EventKey   string
+	EventType  service.EventType
+	TaskID     string
+	Attempt    int
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	At         time.Time
+	Success    bool
+}
+
+// RetryPolicy — число попыток доставки и экспоненциальная задержка между ними
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Minute}
+
+// Service хранит вебхуки пользователей в Store и доставляет на них события задач
+type Service struct {
+	store  Store
+	guard  Guard
+	client *http.Client
+	policy RetryPolicy
+	// sem ограничивает число одновременных запросов к получателям
+	sem chan struct{}
+	// wake будит Run после постановки доставок в очередь
+	wake   chan struct{}
+	logger *logrus.Logger
+}
+
+// NewService создаёт сервис вебхуков. Адреса проверяются guard при регистрации;
+// client должен проверять их и при соединении — см. Guard.Transport.
+func NewService(store Store, guard Guard, client *http.Client, policy RetryPolicy, logger *logrus.Logger) *Service {
+	return &Service{
+		store:  store,
+		guard:  guard,
+		client: client,
+		policy: policy,
+		sem:    make(chan struct{}, 16),
+		wake:   make(chan struct{}, 1),
+		logger: logger,
+	}
+}
+
+// Register добавляет вебхук владельцу owner. Если secret пуст, он генерируется;
+// возвращённый Webhook — единственное место, где секрет виден после создания.
+func (s *Service) Register(ctx context.Context, owner, rawURL string, events []service.EventType, secret string) (Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return Webhook{}, fmt.Errorf("%w: url must not contain credentials", ErrInvalidWebhook)
+	}
+	if err := s.guard.checkURL(ctx, u); err != nil {
+		return Webhook{}, fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	if len(events) == 0 {
+		return Webhook{}, fmt.Errorf("%w: at least one event type is required", ErrInvalidWebhook)
+	}
+	for _, t := range events {
+		switch t {
+		case service.EventCreated, service.EventUpdated, service.EventDeleted:
+		default:
+			return Webhook{}, fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, t)
+		}
+	}
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return Webhook{}, err
+		}
+	}
+	events = slices.Clone(events)
+	slices.Sort(events)
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Webhook{}, fmt.Errorf("generate webhook id: %w", err)
+	}
+
+	hook := Webhook{
+		ID:        "wh_" + id.String(),
+		Owner:     owner,
+		URL:       u.String(),
+		Events:    slices.Compact(events),
+		Secret:    secret,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := s.store.CreateWebhook(ctx, hook); err != nil {
+		return Webhook{}, fmt.Errorf("save webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// List возвращает вебхуки владельца в порядке создания
+func (s *Service) List(ctx context.Context, owner string) ([]Webhook, error) {
+	return s.store.ListWebhooks(ctx, owner)
+}
+
+// Delete удаляет вебхук; незавершённые доставки на него прекращаются
+func (s *Service) Delete(ctx context.Context, owner, id string) error {
+	if _, err := s.owned(ctx, owner, id); err != nil {
+		return err
+	}
+	return s.store.DeleteWebhook(ctx, id)
+}
+
+// Deliveries возвращает журнал попыток доставки, начиная с последней
+func (s *Service) Deliveries(ctx context.Context, owner, id string) ([]Delivery, error) {
+	if _, err := s.owned(ctx, owner, id); err != nil {
+		return nil, err
+	}
+	return s.store.ListDeliveries(ctx, id)
+}
+
+// owned возвращает вебхук id, если он принадлежит owner; чужой вебхук не отличить от несуществующего
+func (s *Service) owned(ctx context.Context, owner, id string) (Webhook, error) {
+	hook, err := s.store.GetWebhook(ctx, id)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if hook.Owner != owner {
+		return Webhook{}, ErrNotFound
+	}
+	return hook, nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}